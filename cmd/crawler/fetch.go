@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	maxAttempts    = 5               // Максимальное кол-во попыток запроса
+	initialBackoff = 1 * time.Second // Пауза перед первым повтором, далее удваивается
+)
+
+// fetcher выполняет HTTP запросы с ограничением частоты запросов к каждому хосту
+// и с соблюдением правил robots.txt
+type fetcher struct {
+	client    *http.Client
+	limiter   *hostLimiter
+	robots    *robotsCache
+	userAgent string
+}
+
+// fetch выполняет GET запрос с дополнительными заголовками и повторяет его с экспоненциальной
+// паузой при временных ошибках: сетевых ошибках, 429 и 5xx. Ответы 200 и 304 возвращаются
+// вызывающему, остальные статусы считаются постоянной ошибкой. Для URL, запрещенных в robots.txt,
+// возвращается errDisallowed
+func (f *fetcher) fetch(ctx context.Context, url string, headers map[string]string) (*http.Response, error) {
+	allowed, err := f.robots.allowed(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errDisallowed
+	}
+
+	backoff := initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		if err := f.limiter.Wait(ctx, url); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", f.userAgent)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := f.client.Do(req)
+		if err == nil {
+			switch {
+			case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified:
+				return resp, nil
+			case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+				resp.Body.Close()
+				err = fmt.Errorf("server returned error status code: %v", resp.StatusCode)
+			default:
+				resp.Body.Close()
+				return nil, fmt.Errorf("server returned error status code: %v", resp.StatusCode)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if attempt == maxAttempts {
+			return nil, fmt.Errorf("get %s failed after %d attempts: %w", url, attempt, err)
+		}
+
+		log.Printf("get %s error: %v, retrying in %v", url, err, backoff)
+
+		if err := sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// sleep ждет d или отмены контекста
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
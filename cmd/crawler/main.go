@@ -1,121 +1,371 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"oip-course/internal/config"
+	"oip-course/internal/documents"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 const (
-	totalPages = 100                                 // Кол-во страниц для выкачки
-	outputDir  = "pages"                             // Название директории для выкаченных веб-страниц
-	baseURL    = "https://elementy.ru/novosti_nauki" // URL ресурса, с которого берутся страницы
-	baseDomain = "https://elementy.ru"               // Домашняя страница ресурса
+	outputDir = "pages"            // Название директории для выкаченных веб-страниц
+	indexFile = "index.txt"        // Файл с номерами страниц и их URL
+	stateFile = "crawl_state.json" // Файл состояния краулера между запусками
+
+	defaultUserAgent = "oip-course-crawler/1.0" // User-Agent краулера по умолчанию
+)
+
+// pageStatus итог обработки статьи
+type pageStatus int
+
+const (
+	statusFetched pageStatus = iota // Статья новая или изменилась и была сохранена
+	statusSkipped                   // Статья не изменилась, дублирует другую, запрещена robots.txt или обработка была отменена
+	statusFailed                    // Статью не удалось выкачать или сохранить
 )
 
+// pageResult результат выкачивания статьи воркером
+type pageResult struct {
+	url          string
+	notModified  bool
+	html         string
+	hash         string
+	etag         string
+	lastModified string
+	document     *documents.Document
+	err          error
+}
+
+// summary итоговая статистика запуска краулера
+type summary struct {
+	fetched, skipped, failed int
+}
+
+func (s *summary) add(status pageStatus) {
+	switch status {
+	case statusFetched:
+		s.fetched++
+	case statusSkipped:
+		s.skipped++
+	case statusFailed:
+		s.failed++
+	}
+}
+
 func main() {
+	profilePath := flag.String("profile", config.DefaultProfilePath, "path to site profile")
+	concurrency := flag.Int("concurrency", 4, "number of concurrent page downloads")
+	rate := flag.Float64("rate", 10, "max requests per second to a single host")
+	burst := flag.Int("burst", 1, "max burst of requests to a single host")
+	userAgent := flag.String("user-agent", defaultUserAgent, "User-Agent header and robots.txt agent name")
+	flag.Parse()
+
+	if *concurrency < 1 || *rate <= 0 || *burst < 1 {
+		log.Fatal("concurrency, rate and burst must be positive")
+	}
+
+	profile, err := config.LoadSiteProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("load site profile error: %v", err)
+	}
+
+	// По SIGINT прекращаем новые запросы и сохраняем уже выкачанные страницы
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Создаем директорию для сохранения страниц
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		log.Fatalf("create output directory error: %v", err)
 	}
 
-	// Создаем файл index.txt для записи номера страницы и URL
-	indexFile, err := os.Create("index.txt")
+	state, err := loadState(stateFile, indexFile, outputDir)
+	if err != nil {
+		log.Fatalf("load crawl state error: %v", err)
+	}
+
+	store, err := documents.LoadStore(documents.DefaultStorePath)
 	if err != nil {
-		log.Fatalf("create index.txt error: %v", err)
+		log.Fatalf("load documents error: %v", err)
 	}
-	defer indexFile.Close()
 
-	writer := bufio.NewWriter(indexFile)
-	defer writer.Flush()
+	// Открываем index.txt на дозапись, новые страницы получают следующие номера
+	index, err := os.OpenFile(indexFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Fatalf("open index.txt error: %v", err)
+	}
+	defer index.Close()
 
-	urls := make([]string, 0, totalPages)
-	basePageNumber := 0
+	client := &http.Client{Timeout: 30 * time.Second}
+	limiter := newHostLimiter(*rate, *burst)
 
-	for len(urls) < totalPages {
-		url := fmt.Sprintf("%s?page=%d", baseURL, basePageNumber)
-		resp, err := http.Get(url)
+	f := &fetcher{
+		client:    client,
+		limiter:   limiter,
+		robots:    newRobotsCache(client, limiter, *userAgent),
+		userAgent: *userAgent,
+	}
+
+	urls := collectURLs(ctx, f, profile)
+	results := downloadPages(ctx, f, state, profile, urls, *concurrency)
+
+	// Сохраняем результаты в порядке ссылок в ленте, чтобы нумерация страниц не зависела от порядка завершения воркеров
+	var stats summary
+	for _, result := range results {
+		stats.add(commitPage(state, store, index, result))
+	}
+
+	log.Printf("Summary: fetched %d, skipped %d, failed %d", stats.fetched, stats.skipped, stats.failed)
+}
+
+// collectURLs собирает ссылки на статьи из ленты сайта, пропуская повторяющиеся
+func collectURLs(ctx context.Context, f *fetcher, profile *config.SiteProfile) []string {
+	urls := make([]string, 0, profile.PageLimit)
+	seen := make(map[string]bool)
+	basePageNumber := profile.FirstListingPage
+
+	for len(urls) < profile.PageLimit {
+		listingURL := profile.ListingPageURL(basePageNumber)
+		base, err := url.Parse(listingURL)
 		if err != nil {
-			log.Fatalf("get page error: %v", err)
+			log.Printf("parse listing url error: %v", err)
+			break
 		}
 
-		if resp.StatusCode != 200 {
-			log.Fatalf("server returned error status code: %v", resp.StatusCode)
+		resp, err := f.fetch(ctx, listingURL, nil)
+		if err != nil {
+			log.Printf("get listing page error: %v", err)
+			break
 		}
 
 		doc, err := goquery.NewDocumentFromReader(resp.Body)
+		resp.Body.Close()
 		if err != nil {
-			log.Fatal(err)
+			log.Printf("parse listing page error: %v", err)
+			break
 		}
 
-		resp.Body.Close()
+		found := 0
 
 		// Ищем все ссылки <a> и добавляем в urls
-		doc.Find("div.clblock.newslist div.img_block32 a.nohover").Each(func(i int, s *goquery.Selection) {
+		doc.Find(profile.LinkSelector).Each(func(i int, s *goquery.Selection) {
 			// Извлекаем значение атрибута href
-			if href, exists := s.Attr("href"); exists {
-				if !strings.HasSuffix(href, ".js") && !strings.HasSuffix(href, ".css") && len(urls) < totalPages {
-					urls = append(urls, baseDomain+href)
-				}
+			href, exists := s.Attr("href")
+			if !exists || strings.HasSuffix(href, ".js") || strings.HasSuffix(href, ".css") || len(urls) >= profile.PageLimit {
+				return
+			}
+
+			// Относительные ссылки разрешаем относительно страницы ленты
+			ref, err := url.Parse(href)
+			if err != nil {
+				return
+			}
+
+			pageURL := base.ResolveReference(ref).String()
+			if !seen[pageURL] {
+				seen[pageURL] = true
+				urls = append(urls, pageURL)
+				found++
 			}
 		})
 
+		// Лента закончилась
+		if found == 0 {
+			break
+		}
+
 		basePageNumber++
-		time.Sleep(100 * time.Millisecond)
 	}
 
-	for i, pageURL := range urls {
-		resp, err := http.Get(pageURL)
-		if err != nil {
-			log.Fatalf("get page error: %v", err)
-		}
+	return urls
+}
 
-		if resp.StatusCode != 200 {
-			log.Fatalf("server returned error status code: %v", resp.StatusCode)
-		}
+// downloadPages выкачивает статьи пулом из concurrency воркеров. Результаты возвращаются в порядке urls
+func downloadPages(ctx context.Context, f *fetcher, state *crawlState, profile *config.SiteProfile, urls []string, concurrency int) []*pageResult {
+	results := make([]*pageResult, len(urls))
+	jobs := make(chan int)
 
-		doc, err := goquery.NewDocumentFromReader(resp.Body)
-		if err != nil {
-			log.Fatal(err)
-		}
-		resp.Body.Close()
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = downloadPage(ctx, f, state.Pages[urls[i]], profile, urls[i])
+			}
+		}()
+	}
 
-		doc.Find("noscript").Each(func(i int, s *goquery.Selection) {
-			s.ReplaceWithHtml(s.Text())
-		})
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 
-		// Удаляем теги <script> и <link rel='stylesheet'>
-		doc.Find("script, link[rel='stylesheet']").Each(func(i int, s *goquery.Selection) {
-			s.Remove()
-		})
+	return results
+}
 
-		cleanedHtml, err := doc.Html()
-		if err != nil {
-			log.Fatalf("get cleaned html error: %v", err)
-		}
+// downloadPage выкачивает и очищает статью и извлекает ее метаданные. Для уже известной статьи выполняется условный запрос
+func downloadPage(ctx context.Context, f *fetcher, known *pageState, profile *config.SiteProfile, pageURL string) *pageResult {
+	result := &pageResult{url: pageURL}
 
-		filename := fmt.Sprintf("%s/page_%d.html", outputDir, i+1)
+	// Условный запрос: сервер ответит 304, если страница не менялась
+	headers := make(map[string]string)
+	if known != nil && known.ETag != "" {
+		headers["If-None-Match"] = known.ETag
+	}
+	if known != nil && known.LastModified != "" {
+		headers["If-Modified-Since"] = known.LastModified
+	}
 
-		// Сохраняем страницу
-		err = os.WriteFile(filename, []byte(cleanedHtml), 0755)
-		if err != nil {
-			log.Fatalf("write file error: %v", err)
+	resp, err := f.fetch(ctx, pageURL, headers)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		result.notModified = true
+		return result
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	doc.Find("noscript").Each(func(i int, s *goquery.Selection) {
+		s.ReplaceWithHtml(s.Text())
+	})
+
+	// Удаляем теги <script> и <link rel='stylesheet'>
+	doc.Find("script, link[rel='stylesheet']").Each(func(i int, s *goquery.Selection) {
+		s.Remove()
+	})
+
+	cleanedHtml, err := doc.Html()
+	if err != nil {
+		result.err = fmt.Errorf("get cleaned html error: %w", err)
+		return result
+	}
+
+	// Хешируем только текст статьи: счетчики, баннеры и токены в остальной разметке меняются от запроса к запросу,
+	// и по хешу всего HTML неизмененная статья выглядела бы измененной, а дубликат под другим URL - новой статьей.
+	// Страница без блока статьи хешируется целиком, иначе все такие страницы считались бы дубликатами друг друга
+	var content strings.Builder
+	doc.Find(profile.ContentSelector).Each(func(i int, s *goquery.Selection) {
+		content.WriteString(s.Text())
+		content.WriteString("\n")
+	})
+	if strings.TrimSpace(content.String()) == "" {
+		content.Reset()
+		content.WriteString(cleanedHtml)
+	}
+	sum := sha256.Sum256([]byte(content.String()))
+
+	result.html = cleanedHtml
+	result.hash = hex.EncodeToString(sum[:])
+	result.etag = resp.Header.Get("ETag")
+	result.lastModified = resp.Header.Get("Last-Modified")
+	result.document = documents.Extract(doc, profile.Metadata)
+	result.document.URL = pageURL
+
+	return result
+}
+
+// commitPage сохраняет выкачанную статью и ее метаданные, если она новая или изменилась с прошлого запуска.
+// Новая статья получает следующий номер и дописывается в index.txt, измененная перезаписывается под прежним номером
+func commitPage(state *crawlState, store *documents.Store, index *os.File, result *pageResult) pageStatus {
+	switch {
+	case errors.Is(result.err, context.Canceled):
+		log.Printf("Cancelled: %s", result.url)
+		return statusSkipped
+	case errors.Is(result.err, errDisallowed):
+		log.Printf("Disallowed by robots.txt: %s", result.url)
+		return statusSkipped
+	case result.err != nil:
+		log.Printf("skip page %s: %v", result.url, result.err)
+		return statusFailed
+	case result.notModified:
+		log.Printf("Not modified: %s", result.url)
+		return statusSkipped
+	}
+
+	known, isKnown := state.Pages[result.url]
+
+	page := &pageState{
+		ETag:         result.etag,
+		LastModified: result.lastModified,
+		Hash:         result.hash,
+		FetchedAt:    time.Now(),
+	}
+
+	switch {
+	case isKnown && known.Hash == result.hash:
+		log.Printf("Unchanged page: %s", result.url)
+		page.Number = known.Number
+		state.Pages[result.url] = page
+		if err := state.save(stateFile); err != nil {
+			log.Printf("save crawl state error: %v", err)
+			return statusFailed
+		}
+		return statusSkipped
+	case isKnown:
+		page.Number = known.Number
+	default:
+		// Та же статья могла попасть в ленту под другим URL
+		if duplicate, found := state.findByHash(result.hash); found {
+			log.Printf("Duplicate of %s: %s", duplicate, result.url)
+			return statusSkipped
 		}
+		page.Number = state.NextPage
+	}
 
-		// Пишем в index.txt
-		_, err = fmt.Fprintf(writer, "%d %s\n", i+1, pageURL)
-		if err != nil {
+	filename := fmt.Sprintf("%s/page_%d.html", outputDir, page.Number)
+
+	// Сохраняем страницу
+	if err := os.WriteFile(filename, []byte(result.html), 0755); err != nil {
+		log.Printf("write file error: %v", err)
+		return statusFailed
+	}
+
+	// Новую страницу дописываем в index.txt сразу, чтобы он не разошелся с состоянием при прерывании
+	if !isKnown {
+		if _, err := fmt.Fprintf(index, "%d %s\n", page.Number, result.url); err != nil {
 			log.Printf("write to index.txt error: %v", err)
+			return statusFailed
 		}
+		state.NextPage++
+	}
 
-		log.Printf("Saved page: %s", pageURL)
+	state.Pages[result.url] = page
+	if err := state.save(stateFile); err != nil {
+		log.Printf("save crawl state error: %v", err)
+		return statusFailed
+	}
 
-		time.Sleep(100 * time.Millisecond)
+	result.document.Page = page.Number
+	store.Put(result.document)
+	if err := store.Save(documents.DefaultStorePath); err != nil {
+		log.Printf("save documents error: %v", err)
 	}
+
+	log.Printf("Saved page %d: %s", page.Number, result.url)
+
+	return statusFetched
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tokenBucket ограничитель частоты запросов: токены пополняются со скоростью rate в секунду
+// до burst штук, каждый запрос забирает один токен
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait ждет, пока в ведре появится токен, или отмены контекста
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+
+		// Время до появления следующего токена
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// hostLimiter хранит отдельное ведро токенов для каждого хоста
+type hostLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*tokenBucket
+}
+
+func newHostLimiter(rate float64, burst int) *hostLimiter {
+	return &hostLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Wait ждет разрешения на запрос к хосту из rawURL
+func (l *hostLimiter) Wait(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	return l.bucket(u.Host).Wait(ctx)
+}
+
+// SetMinInterval ограничивает запросы к хосту не чаще одного за interval
+func (l *hostLimiter) SetMinInterval(host string, interval time.Duration) {
+	bucket := l.bucket(host)
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.rate = min(bucket.rate, 1/interval.Seconds())
+	bucket.burst = 1
+	bucket.tokens = min(bucket.tokens, bucket.burst)
+}
+
+// bucket возвращает ведро токенов хоста, создавая его при первом обращении
+func (l *hostLimiter) bucket(host string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = newTokenBucket(l.rate, l.burst)
+		l.buckets[host] = bucket
+	}
+
+	return bucket
+}
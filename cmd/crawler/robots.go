@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"oip-course/internal/robots"
+	"sync"
+)
+
+// errDisallowed ошибка запроса к URL, обход которого запрещен в robots.txt
+var errDisallowed = errors.New("disallowed by robots.txt")
+
+// hostRobots правила robots.txt хоста, загружаемые один раз
+type hostRobots struct {
+	once  sync.Once
+	rules *robots.Rules
+}
+
+// robotsCache загружает robots.txt при первом обращении к хосту и хранит правила до конца запуска
+type robotsCache struct {
+	mu        sync.Mutex
+	client    *http.Client
+	limiter   *hostLimiter
+	userAgent string
+	hosts     map[string]*hostRobots
+}
+
+func newRobotsCache(client *http.Client, limiter *hostLimiter, userAgent string) *robotsCache {
+	return &robotsCache{
+		client:    client,
+		limiter:   limiter,
+		userAgent: userAgent,
+		hosts:     make(map[string]*hostRobots),
+	}
+}
+
+// allowed проверяет, разрешает ли robots.txt обход pageURL. Если в robots.txt указан
+// Crawl-delay, частота запросов к хосту дополнительно ограничивается
+func (c *robotsCache) allowed(ctx context.Context, pageURL string) (bool, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	host, ok := c.hosts[u.Host]
+	if !ok {
+		host = &hostRobots{}
+		c.hosts[u.Host] = host
+	}
+	c.mu.Unlock()
+
+	host.once.Do(func() {
+		rules, err := c.fetch(ctx, pageURL)
+		if err != nil {
+			log.Printf("load robots.txt for %s error: %v", u.Host, err)
+		}
+		if rules == nil {
+			rules = robots.DisallowAll()
+		}
+
+		if delay := rules.CrawlDelay(); delay > 0 {
+			log.Printf("Crawl-delay for %s: %v", u.Host, delay)
+			c.limiter.SetMinInterval(u.Host, delay)
+		}
+
+		host.rules = rules
+	})
+
+	return host.rules.Allowed(u.RequestURI()), nil
+}
+
+// fetch загружает robots.txt хоста pageURL и повторяет запрос с экспоненциальной паузой при ошибках сети и 5xx,
+// как и запросы страниц. Если все попытки неудачны, возвращаются правила, запрещающие обход
+func (c *robotsCache) fetch(ctx context.Context, pageURL string) (*robots.Rules, error) {
+	backoff := initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(ctx, pageURL); err != nil {
+			return robots.DisallowAll(), err
+		}
+
+		rules, err := robots.Fetch(ctx, c.client, pageURL, c.userAgent)
+		if err == nil {
+			return rules, nil
+		}
+
+		if ctx.Err() != nil {
+			return robots.DisallowAll(), ctx.Err()
+		}
+
+		if attempt == maxAttempts {
+			return robots.DisallowAll(), fmt.Errorf("failed after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("load robots.txt error: %v, retrying in %v", err, backoff)
+
+		if err := sleep(ctx, backoff); err != nil {
+			return robots.DisallowAll(), err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pageState сохраненная информация о выкачанной странице
+type pageState struct {
+	Number       int       `json:"number"`                  // Номер страницы в pages/ и index.txt
+	ETag         string    `json:"etag,omitempty"`          // Заголовок ETag последнего ответа
+	LastModified string    `json:"last_modified,omitempty"` // Заголовок Last-Modified последнего ответа
+	Hash         string    `json:"hash,omitempty"`          // SHA-256 текста статьи
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// crawlState состояние краулера между запусками
+type crawlState struct {
+	NextPage int                   `json:"next_page"` // Номер, который получит следующая новая страница
+	Pages    map[string]*pageState `json:"pages"`     // URL -> информация о странице
+}
+
+// loadState загружает состояние краулера. Если файла состояния нет, состояние восстанавливается
+// из index.txt и директории страниц, чтобы не перезаписать уже выкачанные страницы
+func loadState(filename, indexFilename, pagesDir string) (*crawlState, error) {
+	state := &crawlState{
+		NextPage: 1,
+		Pages:    make(map[string]*pageState),
+	}
+
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("decode crawl state: %w", err)
+		}
+		return state, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// Новые страницы нумеруются после уже лежащих в директории
+	items, err := os.ReadDir(pagesDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, item := range items {
+		var number int
+		if _, err := fmt.Sscanf(item.Name(), "page_%d.html", &number); err == nil {
+			state.NextPage = max(state.NextPage, number+1)
+		}
+	}
+
+	indexFile, err := os.Open(indexFilename)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer indexFile.Close()
+
+	scanner := bufio.NewScanner(indexFile)
+	for scanner.Scan() {
+		var (
+			number int
+			url    string
+		)
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %s", &number, &url); err != nil {
+			continue
+		}
+
+		state.Pages[url] = &pageState{Number: number}
+		state.NextPage = max(state.NextPage, number+1)
+	}
+
+	return state, scanner.Err()
+}
+
+// save атомарно записывает состояние краулера в файл
+func (s *crawlState) save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// findByHash возвращает URL страницы с таким же содержимым, если она уже выкачана
+func (s *crawlState) findByHash(hash string) (string, bool) {
+	for url, page := range s.Pages {
+		if page.Hash == hash {
+			return url, true
+		}
+	}
+
+	return "", false
+}
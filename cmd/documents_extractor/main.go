@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"oip-course/internal/config"
+	"oip-course/internal/documents"
+	"oip-course/internal/storage"
+	"os"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	pagesDir      = "pages"
+	indexFileName = "index.txt"
+)
+
+func main() {
+	profilePath := flag.String("profile", config.DefaultProfilePath, "path to site profile")
+	flag.Parse()
+
+	profile, err := config.LoadSiteProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("load site profile error: %v", err)
+	}
+
+	urls, err := storage.LoadPageURLs(indexFileName)
+	if err != nil {
+		log.Printf("load page urls error: %v", err)
+	}
+
+	// Читаем все файлы в директории pages
+	items, err := os.ReadDir(pagesDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store := documents.NewStore()
+
+	for _, item := range items {
+		var pageNum int
+		_, err = fmt.Sscanf(item.Name(), "page_%d.html", &pageNum)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		pageFile, err := os.Open(pagesDir + "/" + item.Name())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		doc, err := goquery.NewDocumentFromReader(pageFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pageFile.Close()
+
+		document := documents.Extract(doc, profile.Metadata)
+		document.Page = pageNum
+		document.URL = urls[pageNum]
+
+		// Если страницы нет в index.txt, берем канонический URL страницы
+		if document.URL == "" {
+			document.URL, _ = doc.Find("link[rel='canonical']").Attr("href")
+		}
+
+		store.Put(document)
+	}
+
+	if err = store.Save(documents.DefaultStorePath); err != nil {
+		log.Fatal(err)
+	}
+}
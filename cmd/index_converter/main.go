@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"oip-course/internal/storage"
+)
+
+// Конвертер инвертированного индекса из JSON в бинарный сегмент
+func main() {
+	input := flag.String("input", "inverted_index.json", "JSON inverted index")
+	output := flag.String("output", storage.DefaultIndexPath, "binary segment file")
+	flag.Parse()
+
+	index, err := storage.LoadInvertedIndex(*input)
+	if err != nil {
+		log.Fatalf("load index error: %v", err)
+	}
+
+	if err := storage.SaveIndex(*output, index); err != nil {
+		log.Fatalf("write segment error: %v", err)
+	}
+
+	log.Printf("Converted %s to %s", *input, *output)
+}
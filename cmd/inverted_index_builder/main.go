@@ -2,28 +2,32 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"oip-course/internal/analysis"
+	"oip-course/internal/config"
+	"oip-course/internal/documents"
 	"oip-course/internal/models"
+	"oip-course/internal/storage"
 	"os"
 	"strings"
+	"time"
 )
 
-const (
-	lemmasDir = "lemmas"
-)
+const tokensDir = "tokens"
 
-// processFile читает файл, извлекает леммы и добавляет номер страницы в инвертированный индекс
-func processFile(fileName string, ii *models.InvertedIndex) {
+// processFile читает файл токенов страницы и добавляет в поле текста статьи инвертированного индекса позиции токенов
+// для лемм, к которым их приводит анализатор, и длину текста
+func processFile(fileName string, ii *models.InvertedIndex, analyzer *analysis.Analyzer) {
 	// Получаем номер страницы
 	var pageNum int
-	_, err := fmt.Sscanf(fileName, "lemmas_%d.txt", &pageNum)
+	_, err := fmt.Sscanf(fileName, "tokens_%d.txt", &pageNum)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	file, err := os.Open(lemmasDir + "/" + fileName)
+	file, err := os.Open(tokensDir + "/" + fileName)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -31,56 +35,123 @@ func processFile(fileName string, ii *models.InvertedIndex) {
 
 	scanner := bufio.NewScanner(file)
 
-	// Построчно обрабатываем файл, достаем лемму и записываем в индекс
+	// Токены записаны в порядке следования на странице, поэтому номер строки - позиция токена
+	position := 0
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, ":")
-		if len(parts) < 2 {
+		token := strings.TrimSpace(scanner.Text())
+		if token == "" {
 			continue
 		}
-		lemma := strings.TrimSpace(parts[0])
-		if lemma == "" {
-			continue
+
+		if lemma := analyzer.Lemma(token); lemma != "" {
+			ii.Add(models.FieldBody, lemma, pageNum, position)
 		}
 
-		ii.Add(lemma, pageNum)
+		position++
 	}
 	if err = scanner.Err(); err != nil {
 		log.Fatal(err)
 	}
+
+	ii.SetDocLength(models.FieldBody, pageNum, position)
+}
+
+// processDocument добавляет в поля заголовка, лида и рубрик инвертированного индекса позиции лемм из метаданных статьи и длины полей.
+// Рубрики разделяются пропуском позиции, чтобы фраза не могла начаться в одной рубрике и закончиться в другой
+func processDocument(document *documents.Document, ii *models.InvertedIndex, analyzer *analysis.Analyzer) {
+	titleLength := addField(ii, analyzer, models.FieldTitle, document.Page, document.Title, 0)
+	ii.SetDocLength(models.FieldTitle, document.Page, titleLength)
+
+	leadLength := addField(ii, analyzer, models.FieldLead, document.Page, document.Lead, 0)
+	ii.SetDocLength(models.FieldLead, document.Page, leadLength)
+
+	position, length := 0, 0
+	for _, tag := range document.Tags {
+		next := addField(ii, analyzer, models.FieldTags, document.Page, tag, position)
+		length += next - position
+		position = next + 1
+	}
+	ii.SetDocLength(models.FieldTags, document.Page, length)
+}
+
+// addField добавляет в поле индекса леммы токенов текста, начиная с позиции start, и возвращает следующую позицию
+func addField(ii *models.InvertedIndex, analyzer *analysis.Analyzer, field string, page int, text string, start int) int {
+	position := start
+	for _, lemma := range analyzer.Lemmas(text) {
+		ii.Add(field, lemma, page, position)
+		position++
+	}
+
+	return position
 }
 
 func main() {
-	ii := models.NewInvertedIndex(make(map[string][]int))
+	output := flag.String("output", storage.DefaultIndexPath, "index file, written as JSON if it has .json extension, otherwise as binary segment")
+	profilePath := flag.String("profile", config.DefaultProfilePath, "path to site profile with analyzer settings")
+	normalizer := flag.String("normalizer", "", "term normalizer overriding the profile: lemmatizer:<langs>, stemmer:ru or hybrid:<langs>")
+	flag.Parse()
 
-	// Получаем список файлов лемм
-	items, err := os.ReadDir(lemmasDir)
+	profile, err := config.LoadSiteProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("load site profile error: %v", err)
+	}
+
+	analyzer, err := profile.NewAnalyzer(*normalizer)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Запускаем обработку лемм для каждого файла
+	ii := models.NewInvertedIndex(make(map[string]map[string]map[int][]int), make(map[string]map[int]int))
+
+	// Получаем список файлов токенов
+	items, err := os.ReadDir(tokensDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Запускаем обработку токенов для каждого файла. Обработанные файлы запоминаем, чтобы записать их хэши в заголовок индекса
+	var sources []string
 	for _, item := range items {
 		if item.IsDir() {
 			continue
 		}
 		name := item.Name()
-		if !strings.HasPrefix(name, "lemmas_") || !strings.HasSuffix(name, ".txt") {
+		if !strings.HasPrefix(name, "tokens_") || !strings.HasSuffix(name, ".txt") {
 			continue
 		}
 
-		processFile(name, ii)
+		processFile(name, ii, analyzer)
+		sources = append(sources, tokensDir+"/"+name)
 	}
 
-	// Преобразуем индекс в формат JSON
-	jsonData, err := json.MarshalIndent(ii.GetIndex(), "", "  ")
+	// Заголовок, лид и рубрики берем из метаданных статей
+	store, err := documents.LoadStore(documents.DefaultStorePath)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("load documents error: %v", err)
 	}
 
-	// Записываем JSON в файл
-	err = os.WriteFile("inverted_index.json", jsonData, 0755)
+	for _, page := range ii.AllPages() {
+		if document, ok := store.Get(page); ok {
+			processDocument(document, ii, analyzer)
+		}
+	}
+	if _, err := os.Stat(documents.DefaultStorePath); err == nil {
+		sources = append(sources, documents.DefaultStorePath)
+	}
+
+	hashes, err := storage.HashFiles(sources)
 	if err != nil {
 		log.Fatal(err)
 	}
+	ii.SetMetadata(models.Metadata{
+		Version:  models.MetadataVersion,
+		Analyzer: analyzer.Config(),
+		DocCount: len(ii.AllPages()),
+		BuiltAt:  time.Now().UTC().Truncate(time.Second),
+		Sources:  hashes,
+	})
+
+	if err := storage.SaveIndex(*output, ii); err != nil {
+		log.Fatal(err)
+	}
 }
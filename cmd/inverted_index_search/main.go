@@ -2,368 +2,301 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"cmp"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"oip-course/internal/analysis"
+	"oip-course/internal/config"
+	"oip-course/internal/documents"
 	"oip-course/internal/models"
+	"oip-course/internal/search"
+	"oip-course/internal/spelling"
+	"oip-course/internal/storage"
 	"os"
-	"sort"
+	"slices"
 	"strings"
+	"unicode"
+)
+
+const (
+	lemmasDir      = "lemmas"
+	lemmasTfIdfDir = "lemmas_tf_idf"
 
-	"github.com/aaaton/golem/v4"
-	"github.com/aaaton/golem/v4/dicts/ru"
+	prompt = "> " // Приглашение к вводу запроса
+
+	scorerCommand = ":scorer" // Команда смены модели ранжирования в ранжированном режиме
 )
 
-var lemmatizer *golem.Lemmatizer
+// rankedOptions настройки ранжированного поиска
+type rankedOptions struct {
+	top    int
+	scorer string             // Модель ранжирования
+	boosts map[string]float64 // Веса полей
+	bm25   models.BM25Params
+}
 
-func init() {
-	var err error
-	lemmatizer, err = golem.New(ru.New())
-	if err != nil {
+func main() {
+	mode := flag.String("mode", "boolean", "search mode: boolean or ranked")
+	indexPath := flag.String("index", storage.DefaultIndexPath, "inverted index file (binary segment or .json)")
+	top := flag.Int("top", 10, "number of results in ranked mode")
+	boostsFlag := flag.String("boosts", "", "field weights in ranked mode, e.g. title=3,tags=2,lead=1.5,body=1")
+	scorer := flag.String("scorer", models.ScorerTFIDF, "ranking model in ranked mode: "+strings.Join(models.Scorers, " or "))
+	k1 := flag.Float64("k1", models.DefaultBM25Params.K1, "BM25 term frequency saturation")
+	b := flag.Float64("b", models.DefaultBM25Params.B, "BM25 document length normalization")
+	profilePath := flag.String("profile", config.DefaultProfilePath, "path to site profile with analyzer settings")
+	normalizer := flag.String("normalizer", "", "query normalizer: lemmatizer:<langs>, stemmer:ru or hybrid:<langs>, by default the one the index was built with")
+	maxExpansions := flag.Int("max-expansions", models.DefaultMaxExpansions, "maximum number of index terms a wildcard may expand to in boolean mode")
+	typoTolerance := flag.Bool("typo-tolerance", false, "in boolean mode search words missing from the index with up to 1-2 typos")
+	forms := flag.String("forms", storage.FormsFromStems, "in boolean mode search words by all their indexed forms: stems groups index terms by stem, a directory - by the tokenizer lemma files in it, empty disables")
+	allowMismatch := flag.Bool("allow-mismatch", false, "only warn if queries are analyzed differently from how the index was built")
+
+	var filter documents.Filter
+	flag.StringVar(&filter.Tag, "tag", "", "show only documents with this rubric")
+	flag.StringVar(&filter.Author, "author", "", "show only documents by this author")
+	flag.StringVar(&filter.From, "from", "", "show only documents published on or after this date (YYYY-MM-DD)")
+	flag.StringVar(&filter.To, "to", "", "show only documents published on or before this date (YYYY-MM-DD)")
+	flag.Parse()
+
+	if err := filter.Validate(); err != nil {
 		log.Fatal(err)
 	}
-}
 
-func main() {
-	index, err := loadInvertedIndex("inverted_index.json")
+	boosts, err := models.ParseBoosts(*boostsFlag)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Создание сканера для чтения пользовательского ввода
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Println("Type 'exit' to quit")
-	fmt.Println("Enter your query:")
-
-	for {
-		fmt.Print("> ")
-		if !scanner.Scan() {
-			break
-		}
-
-		query := strings.TrimSpace(scanner.Text())
-		if query == "exit" {
-			break
-		}
-
-		results := processQuery(query, index)
-		if results == nil {
-			fmt.Println("Results found: 0")
-		} else {
-			fmt.Printf("Results found: %d\n", len(results))
-			fmt.Println(results)
-		}
+	options := rankedOptions{
+		top:    *top,
+		scorer: *scorer,
+		boosts: boosts,
+		bm25:   models.BM25Params{K1: *k1, B: *b},
+	}
+	if !slices.Contains(models.Scorers, options.scorer) {
+		log.Fatalf("unknown scorer: %s", options.scorer)
+	}
+	if err := options.bm25.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *maxExpansions < 1 {
+		log.Fatal("max-expansions must be positive")
 	}
-}
 
-// loadInvertedIndex загружает инвертированный индекс из JSON файла
-func loadInvertedIndex(filename string) (*models.InvertedIndex, error) {
-	file, err := os.Open(filename)
+	profile, err := config.LoadSiteProfile(*profilePath)
 	if err != nil {
-		return nil, err
+		log.Fatalf("load site profile error: %v", err)
 	}
-	defer file.Close()
 
-	var rawIndex map[string][]int
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&rawIndex); err != nil {
-		return nil, err
+	index, err := storage.LoadIndex(*indexPath)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	return models.NewInvertedIndex(rawIndex), nil
-}
-
-// processQuery обрабатывает запрос
-func processQuery(query string, index *models.InvertedIndex) []int {
-	tokens := tokenizeQuery(query)
-
-	if err := validateQuery(tokens); err != nil {
-		fmt.Println("Error: ", err)
-		return nil
+	// Запрос разбирается конвейером анализа профиля с тем нормализатором, которым строился индекс
+	analyzer, err := profile.NewAnalyzer(cmp.Or(*normalizer, index.Metadata().Analyzer.Normalizer()))
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	postfix := infixToPostfix(tokens)
-	return evaluatePostfix(postfix, index)
-}
-
-// validateQuery проверяет корректность запроса
-func validateQuery(tokens []string) error {
-	// Проверка на пустой запрос или запрос только из оператора
-	if len(tokens) == 0 || (len(tokens) == 1 && isOperator(tokens[0])) {
-		return fmt.Errorf("wrong query")
+	warnings, err := storage.VerifyIndex(index, analyzer.Config(), *allowMismatch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, warning := range warnings {
+		log.Printf("warning: %s", warning)
 	}
 
-	// Проверка на оператор в конце запроса
-	if isOperator(tokens[len(tokens)-1]) {
-		return fmt.Errorf("wrong query")
+	store, err := documents.LoadStore(documents.DefaultStorePath)
+	if err != nil {
+		log.Fatalf("load documents error: %v", err)
 	}
 
-	// Проверка баланса скобок и последовательности операторов
-	stack := make([]string, 0)
-	for i := range tokens {
-		switch tokens[i] {
-		case "(":
-			stack = append(stack, tokens[i])
-		case ")":
-			if len(stack) == 0 {
-				return fmt.Errorf("wrong query")
-			}
-			stack = stack[:len(stack)-1]
-		default:
-			// Проверка последовательности операторов
-			if isOperator(tokens[i]) && i+1 < len(tokens) {
-				next := tokens[i+1]
-				// Разрешаем NOT перед любым токеном, кроме закрывающей скобки, для остальных операторов запрещаем
-				if (tokens[i] != "NOT" && isOperator(next)) || next == ")" {
-					return fmt.Errorf("wrong query")
-				}
+	// Исправления предлагаются по словарю того же индекса, в котором ищется запрос, формами слов из текстов
+	surface, err := storage.LoadSurfaceForms(lemmasDir, analyzer.Lemma)
+	if err != nil {
+		log.Fatalf("load surface forms error: %v", err)
+	}
+	corrector := spelling.NewCorrector(index, analyzer, surface)
+
+	switch *mode {
+	case "boolean":
+		evaluate := models.DefaultEvaluateOptions
+		evaluate.MaxExpansions, evaluate.TypoTolerance = *maxExpansions, *typoTolerance
+		if *forms != "" {
+			// Группы строятся тем же конвейером анализа, которым разбираются слова запроса
+			evaluate.Forms, err = storage.LoadWordForms(*forms, index, analyzer)
+			if err != nil {
+				log.Fatalf("load word forms error: %v", err)
 			}
 		}
+		runBooleanSearch(index, analyzer, corrector, store, filter, evaluate)
+	case "ranked":
+		runRankedSearch(index, analyzer, corrector, store, filter, options)
+	default:
+		log.Fatalf("unknown search mode: %s", *mode)
 	}
-
-	if len(stack) > 0 {
-		return fmt.Errorf("wrong query")
-	}
-
-	return nil
 }
 
-// isOperator проверяет, является ли токен оператором
-func isOperator(token string) bool {
-	return token == "AND" || token == "OR" || token == "NOT"
-}
-
-// tokenizeQuery разбивает запрос на токены
-func tokenizeQuery(query string) []string {
-	var tokens []string
-	var currentToken strings.Builder
-
-	for _, char := range query {
-		switch {
-		case char == '(' || char == ')':
-			if currentToken.Len() > 0 {
-				token := currentToken.String()
-
-				switch strings.ToUpper(token) {
-				case "AND", "OR", "NOT":
-					tokens = append(tokens, strings.ToUpper(token))
-				default:
-					tokens = append(tokens, lemmatizer.Lemma(strings.ToLower(token)))
-				}
-
-				currentToken.Reset()
-			}
-
-			tokens = append(tokens, string(char))
-		case char == ' ':
-			if currentToken.Len() > 0 {
-				token := currentToken.String()
-
-				switch strings.ToUpper(token) {
-				case "AND", "OR", "NOT":
-					tokens = append(tokens, strings.ToUpper(token))
-				default:
-					tokens = append(tokens, lemmatizer.Lemma(strings.ToLower(token)))
-				}
+// runBooleanSearch запускает булевый поиск по инвертированному индексу
+func runBooleanSearch(index models.Index, analyzer *analysis.Analyzer, corrector *spelling.Corrector, store *documents.Store, filter documents.Filter, options models.EvaluateOptions) {
+	find := func(query string) ([]int, error) {
+		q, err := search.Parse(query, analyzer)
+		if err != nil {
+			return nil, err
+		}
 
-				currentToken.Reset()
-			}
-		default:
-			currentToken.WriteRune(char)
+		pages, err := models.Evaluate(index, q, options)
+		if err != nil {
+			return nil, err
 		}
+
+		return store.FilterPages(pages, filter), nil
 	}
 
-	if currentToken.Len() > 0 {
-		token := currentToken.String()
+	readQueries(func(query string) {
+		// Запрос, набранный в английской раскладке или транслитом, повторяется в кириллице
+		results, rewritten, err := spelling.Retry(corrector, query, find)
+		if rewritten != "" {
+			printRewrite(rewritten)
+			query = rewritten
+		}
 
-		switch strings.ToUpper(token) {
-		case "AND", "OR", "NOT":
-			tokens = append(tokens, strings.ToUpper(token))
-		default:
-			tokens = append(tokens, lemmatizer.Lemma(strings.ToLower(token)))
+		if err != nil {
+			printParseError(err)
+			fmt.Println("Results found: 0")
+			return
 		}
-	}
 
-	return tokens
+		fmt.Printf("Results found: %d\n", len(results))
+		for i, page := range results {
+			fmt.Printf("%d. %s\n", i+1, describePage(store, page))
+		}
+		printSuggestion(corrector, query)
+	})
 }
 
-// infixToPostfix конвертирует инфиксную нотацию в постфиксную
-func infixToPostfix(tokens []string) []string {
-	var postfix []string
-	var stack []string
-
-	// Приоритеты операторов
-	precedence := map[string]int{
-		"NOT": 3,
-		"AND": 2,
-		"OR":  1,
+// runRankedSearch запускает ранжированный поиск с оценкой страницы по взвешенной сумме оценок полей.
+// Команда ":scorer <модель>" меняет модель ранжирования для следующих запросов
+func runRankedSearch(index models.Index, analyzer *analysis.Analyzer, corrector *spelling.Corrector, store *documents.Store, filter documents.Filter, options rankedOptions) {
+	scorers, err := storage.LoadScorers(index, lemmasTfIdfDir, options.bm25)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	for _, token := range tokens {
-		switch token {
-		case "(":
-			stack = append(stack, token)
-		case ")":
-			// Извлекаем операторы из стека до открывающей скобки
-			for len(stack) > 0 && stack[len(stack)-1] != "(" {
-				postfix = append(postfix, stack[len(stack)-1])
-				stack = stack[:len(stack)-1]
+	// Фильтр применяем до отсечения top-k, чтобы не потерять подходящие страницы
+	rank := func(query string) []models.SearchResult {
+		var results []models.SearchResult
+		for _, result := range models.SearchFields(scorers[options.scorer], options.boosts, analyzer.Lemmas(query), 0) {
+			if len(results) == options.top {
+				break
 			}
-			stack = stack[:len(stack)-1] // Удаление открывающей скобки
-		case "NOT", "AND", "OR":
-			// Извлекаем из стека все операторы с большим или равным приоритетом, чтобы они выполнились раньше
-			for len(stack) > 0 && stack[len(stack)-1] != "(" &&
-				precedence[stack[len(stack)-1]] >= precedence[token] {
-				postfix = append(postfix, stack[len(stack)-1])
-				stack = stack[:len(stack)-1]
+			if store.Matches(result.Page, filter) {
+				results = append(results, result)
 			}
-			stack = append(stack, token)
-		default:
-			postfix = append(postfix, token)
 		}
-	}
 
-	// Извлечение оставшихся операторов из стека
-	for len(stack) > 0 {
-		postfix = append(postfix, stack[len(stack)-1])
-		stack = stack[:len(stack)-1]
+		return results
 	}
 
-	return postfix
-}
-
-// evaluatePostfix вычисляет постфиксное выражение
-func evaluatePostfix(postfix []string, index *models.InvertedIndex) []int {
-	var stack [][]int
+	fmt.Printf("Scorer: %s (change with '%s %s')\n", options.scorer, scorerCommand, strings.Join(models.Scorers, "|"))
 
-	// Получаем все документы из индекса
-	allPages := make(map[int]bool)
-	for _, postings := range index.GetIndex() {
-		for _, pageID := range postings {
-			allPages[pageID] = true
-		}
-	}
-	var allPagesSlice []int
-	for pageID := range allPages {
-		allPagesSlice = append(allPagesSlice, pageID)
-	}
-	sort.Ints(allPagesSlice)
-
-	for _, token := range postfix {
-		switch token {
-		case "AND":
-			// Достаем два последних элемента из стека, выполняем пересечение и кладем результат обратно в стек
-			if len(stack) < 2 {
-				return nil
+	readQueries(func(query string) {
+		if name, found := strings.CutPrefix(strings.TrimSpace(query), scorerCommand); found {
+			name = strings.TrimSpace(name)
+			if _, ok := scorers[name]; !ok {
+				fmt.Printf("Error: unknown scorer %q, available: %s\n", name, strings.Join(models.Scorers, ", "))
+				return
 			}
 
-			right := stack[len(stack)-1]
-			left := stack[len(stack)-2]
-			stack = stack[:len(stack)-2]
+			options.scorer = name
+			fmt.Printf("Scorer: %s\n", name)
+			return
+		}
 
-			sort.Ints(left)
-			sort.Ints(right)
+		// Запрос, набранный в английской раскладке или транслитом, повторяется в кириллице
+		results, rewritten, _ := spelling.Retry(corrector, query, func(query string) ([]models.SearchResult, error) {
+			return rank(query), nil
+		})
+		if rewritten != "" {
+			printRewrite(rewritten)
+			query = rewritten
+		}
 
-			stack = append(stack, intersect(left, right))
-		case "OR":
-			// Достаем два последних элемента из стека, выполняем объединение и кладем результат обратно в стек
-			if len(stack) < 2 {
-				return nil
-			}
-			right := stack[len(stack)-1]
-			left := stack[len(stack)-2]
-			stack = stack[:len(stack)-2]
-
-			sort.Ints(left)
-			sort.Ints(right)
-
-			stack = append(stack, union(left, right))
-		case "NOT":
-			// NOT в начале выражения, тогда нам нужны все страницы
-			if len(stack) < 1 {
-				stack = append(stack, allPagesSlice)
-				continue
-			}
+		fmt.Printf("Results found: %d\n", len(results))
+		for i, result := range results {
+			fmt.Printf("%d. (%.6f) %s\n", i+1, result.Score, describePage(store, result.Page))
+		}
+		printSuggestion(corrector, query)
+	})
+}
 
-			operand := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
+// describePage возвращает строку с номером страницы и ее метаданными для вывода в результатах
+func describePage(store *documents.Store, page int) string {
+	document, ok := store.Get(page)
+	if !ok {
+		return fmt.Sprintf("page %d", page)
+	}
 
-			// Создаем множество страниц для исключения
-			exclude := make(map[int]bool)
-			for _, pageID := range operand {
-				exclude[pageID] = true
-			}
+	description := fmt.Sprintf("page %d", page)
+	if document.Date != "" {
+		description += " " + document.Date
+	}
+	if document.Title != "" {
+		description += " " + document.Title
+	}
+	if len(document.Tags) > 0 {
+		description += " [" + strings.Join(document.Tags, ", ") + "]"
+	}
+	if document.URL != "" {
+		description += " " + document.URL
+	}
 
-			// Записываем в результат все страницы, которые не входят в множество исключения
-			var result []int
-			for _, pageID := range allPagesSlice {
-				if !exclude[pageID] {
-					result = append(result, pageID)
-				}
-			}
+	return description
+}
 
-			stack = append(stack, result)
-		default:
-			// Добавляем отсортированный массив страниц в стек
-			if ids, exists := index.GetIndex()[token]; exists {
-				sorted := make([]int, len(ids))
-				copy(sorted, ids)
-				sort.Ints(sorted)
-				stack = append(stack, sorted)
-			} else {
-				stack = append(stack, []int{})
-			}
-		}
-	}
+// printRewrite сообщает, что запрос был переведен из английской раскладки или транслита
+func printRewrite(rewritten string) {
+	fmt.Printf("No results for the query as typed, showing results for: %s\n", rewritten)
+}
 
-	if len(stack) != 1 {
-		return nil
+// printSuggestion предлагает исправленный запрос, если в запросе есть слова, которых нет в индексе
+func printSuggestion(corrector *spelling.Corrector, query string) {
+	if suggestion, ok := corrector.Suggest(query); ok {
+		fmt.Printf("Did you mean: %s?\n", suggestion)
 	}
-
-	return stack[0]
 }
 
-// intersect выполняет операцию пересечения двух отсортированных массивов
-func intersect(a, b []int) []int {
-	var result []int
-	i, j := 0, 0
-
-	for i < len(a) && j < len(b) {
-		if a[i] == b[j] {
-			result = append(result, a[i])
-			i++
-			j++
-		} else if a[i] < b[j] {
-			i++
-		} else {
-			j++
-		}
+// printParseError выводит ошибку запроса и указывает на место ошибки под строкой ввода
+func printParseError(err error) {
+	var parseErr *search.ParseError
+	if errors.As(err, &parseErr) {
+		fmt.Printf("%s^\n", strings.Repeat(" ", len(prompt)+parseErr.Pos))
 	}
 
-	return result
+	fmt.Println("Error: ", err)
 }
 
-// union выполняет операцию объединения двух отсортированных массивов
-func union(a, b []int) []int {
-	var result []int
-	i, j := 0, 0
-
-	for i < len(a) && j < len(b) {
-		if a[i] == b[j] {
-			result = append(result, a[i])
-			i++
-			j++
-		} else if a[i] < b[j] {
-			result = append(result, a[i])
-			i++
-		} else {
-			result = append(result, b[j])
-			j++
+// readQueries читает запросы пользователя из stdin и передает их в обработчик
+func readQueries(handle func(query string)) {
+	// Создание сканера для чтения пользовательского ввода
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Println("Type 'exit' to quit")
+	fmt.Println("Enter your query:")
+
+	for {
+		fmt.Print(prompt)
+		if !scanner.Scan() {
+			break
 		}
-	}
 
-	result = append(result, a[i:]...)
-	result = append(result, b[j:]...)
+		// Пробелы в начале не обрезаются: позиция ошибки разбора отсчитывается от начала введенной строки
+		query := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		if strings.TrimSpace(query) == "exit" {
+			break
+		}
 
-	return result
+		handle(query)
+	}
 }
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"oip-course/internal/analysis"
+	"oip-course/internal/config"
+	"oip-course/internal/documents"
+	"oip-course/internal/models"
+	"oip-course/internal/search"
+	"oip-course/internal/spelling"
+	"oip-course/internal/storage"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	lemmasDir      = "lemmas"
+	lemmasTfIdfDir = "lemmas_tf_idf"
+
+	defaultTop = 10 // Кол-во результатов ранжированного поиска по умолчанию
+)
+
+// server хранит загруженные при старте индексы
+type server struct {
+	index     models.Index
+	evaluate  models.EvaluateOptions              // Настройки вычисления булевых запросов
+	analyzer  *analysis.Analyzer                  // Конвейер анализа, которым строился индекс
+	corrector *spelling.Corrector                 // Исправление опечаток по словарю индекса
+	scorers   map[string]map[string]models.Scorer // Модели ранжирования по полям документа
+	documents *documents.Store
+}
+
+// pageResult страница в выдаче поиска
+type pageResult struct {
+	Page  int      `json:"page"`
+	URL   string   `json:"url"`
+	Title string   `json:"title,omitempty"`
+	Date  string   `json:"date,omitempty"`
+	Score *float64 `json:"score,omitempty"`
+}
+
+// searchResponse ответ на поисковый запрос
+type searchResponse struct {
+	Query      string       `json:"query"`
+	Total      int          `json:"total"`
+	Results    []pageResult `json:"results"`
+	Rewritten  string       `json:"rewritten,omitempty"`  // Запрос в кириллице, если он был набран в английской раскладке или транслитом
+	Suggestion string       `json:"suggestion,omitempty"` // Исправленный запрос, если в запросе есть слова, которых нет в индексе
+}
+
+// errorResponse ответ с описанием ошибки, для ошибок разбора запроса указывается позиция
+type errorResponse struct {
+	Error    string `json:"error"`
+	Position *int   `json:"position,omitempty"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "HTTP listen address")
+	indexPath := flag.String("index", storage.DefaultIndexPath, "inverted index file (binary segment or .json)")
+	profilePath := flag.String("profile", config.DefaultProfilePath, "path to site profile with analyzer settings")
+	normalizer := flag.String("normalizer", "", "query normalizer: lemmatizer:<langs>, stemmer:ru or hybrid:<langs>, by default the one the index was built with")
+	maxExpansions := flag.Int("max-expansions", models.DefaultMaxExpansions, "maximum number of index terms a wildcard may expand to")
+	typoTolerance := flag.Bool("typo-tolerance", false, "search words missing from the index with up to 1-2 typos")
+	forms := flag.String("forms", storage.FormsFromStems, "search words by all their indexed forms: stems groups index terms by stem, a directory - by the tokenizer lemma files in it, empty disables")
+	allowMismatch := flag.Bool("allow-mismatch", false, "only warn if queries are analyzed differently from how the index was built")
+	flag.Parse()
+
+	if *maxExpansions < 1 {
+		log.Fatal("max-expansions must be positive")
+	}
+
+	profile, err := config.LoadSiteProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("load site profile error: %v", err)
+	}
+
+	index, err := storage.LoadIndex(*indexPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	analyzer, err := profile.NewAnalyzer(cmp.Or(*normalizer, index.Metadata().Analyzer.Normalizer()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	warnings, err := storage.VerifyIndex(index, analyzer.Config(), *allowMismatch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, warning := range warnings {
+		log.Printf("warning: %s", warning)
+	}
+
+	scorers, err := storage.LoadScorers(index, lemmasTfIdfDir, models.DefaultBM25Params)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := documents.LoadStore(documents.DefaultStorePath)
+	if err != nil {
+		log.Fatalf("load documents error: %v", err)
+	}
+
+	evaluate := models.DefaultEvaluateOptions
+	evaluate.MaxExpansions, evaluate.TypoTolerance = *maxExpansions, *typoTolerance
+	if *forms != "" {
+		evaluate.Forms, err = storage.LoadWordForms(*forms, index, analyzer)
+		if err != nil {
+			log.Fatalf("load word forms error: %v", err)
+		}
+	}
+
+	surface, err := storage.LoadSurfaceForms(lemmasDir, analyzer.Lemma)
+	if err != nil {
+		log.Fatalf("load surface forms error: %v", err)
+	}
+
+	s := &server{
+		index:     index,
+		evaluate:  evaluate,
+		analyzer:  analyzer,
+		corrector: spelling.NewCorrector(index, analyzer, surface),
+		scorers:   scorers,
+		documents: store,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/search", s.handleBooleanSearch)
+	mux.HandleFunc("GET /api/ranked", s.handleRankedSearch)
+	mux.HandleFunc("GET /api/documents/{page}", s.handleDocument)
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// handleBooleanSearch выполняет булевый запрос из параметра q.
+// Параметры tag, author, from и to ограничивают выдачу по метаданным документов
+func (s *server) handleBooleanSearch(w http.ResponseWriter, r *http.Request) {
+	// Пробелы в начале не обрезаются: позиция ошибки разбора отсчитывается от начала q, присланного клиентом
+	query := strings.TrimRightFunc(r.URL.Query().Get("q"), unicode.IsSpace)
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Запрос, набранный в английской раскладке или транслитом, повторяется в кириллице
+	pages, rewritten, err := spelling.Retry(s.corrector, query, func(query string) ([]int, error) {
+		return s.booleanSearch(query, filter)
+	})
+
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	results := make([]pageResult, 0, len(pages))
+	for _, page := range pages {
+		results = append(results, s.pageResult(page))
+	}
+
+	writeJSON(w, http.StatusOK, s.searchResponse(query, rewritten, results))
+}
+
+// booleanSearch вычисляет булевый запрос и оставляет страницы, подходящие под фильтр
+func (s *server) booleanSearch(query string, filter documents.Filter) ([]int, error) {
+	q, err := search.Parse(query, s.analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := models.Evaluate(s.index, q, s.evaluate)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.documents.FilterPages(pages, filter), nil
+}
+
+// handleRankedSearch выполняет ранжированный поиск по запросу из параметра q,
+// параметр top ограничивает кол-во результатов, scorer выбирает модель ранжирования (tfidf по умолчанию),
+// параметры фильтра те же, что и у булевого поиска
+func (s *server) handleRankedSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimRightFunc(r.URL.Query().Get("q"), unicode.IsSpace)
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, "empty query")
+		return
+	}
+
+	top := defaultTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "top must be a positive integer")
+			return
+		}
+		top = n
+	}
+
+	scorer := models.ScorerTFIDF
+	if value := r.URL.Query().Get("scorer"); value != "" {
+		scorer = value
+	}
+	fields, ok := s.scorers[scorer]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "scorer must be one of: "+strings.Join(models.Scorers, ", "))
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Запрос, набранный в английской раскладке или транслитом, повторяется в кириллице
+	results, rewritten, _ := spelling.Retry(s.corrector, query, func(query string) ([]pageResult, error) {
+		return s.rankedSearch(fields, query, top, filter), nil
+	})
+
+	writeJSON(w, http.StatusOK, s.searchResponse(query, rewritten, results))
+}
+
+// rankedSearch возвращает top страниц с наибольшей оценкой, подходящих под фильтр
+func (s *server) rankedSearch(fields map[string]models.Scorer, query string, top int, filter documents.Filter) []pageResult {
+	// Фильтр применяем до отсечения top-k, чтобы не потерять подходящие страницы
+	results := make([]pageResult, 0, top)
+	for _, found := range models.SearchFields(fields, models.DefaultBoosts, s.analyzer.Lemmas(query), 0) {
+		if len(results) == top {
+			break
+		}
+		if !s.documents.Matches(found.Page, filter) {
+			continue
+		}
+
+		result := s.pageResult(found.Page)
+		result.Score = &found.Score
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// searchResponse возвращает ответ с результатами запроса. Исправление опечаток предлагается
+// для запроса, по которому получены результаты: переведенного в кириллицу, если он был переведен
+func (s *server) searchResponse(query string, rewritten string, results []pageResult) searchResponse {
+	suggestion, _ := s.corrector.Suggest(cmp.Or(rewritten, query))
+	return searchResponse{Query: query, Total: len(results), Results: results, Rewritten: rewritten, Suggestion: suggestion}
+}
+
+// handleDocument возвращает метаданные документа по номеру страницы
+func (s *server) handleDocument(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.PathValue("page"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "page must be an integer")
+		return
+	}
+
+	document, ok := s.documents.Get(page)
+	if !ok {
+		writeError(w, http.StatusNotFound, "page not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, document)
+}
+
+// pageResult возвращает страницу в выдаче с URL, заголовком и датой из метаданных документа
+func (s *server) pageResult(page int) pageResult {
+	result := pageResult{Page: page}
+	if document, ok := s.documents.Get(page); ok {
+		result.URL = document.URL
+		result.Title = document.Title
+		result.Date = document.Date
+	}
+
+	return result
+}
+
+// parseFilter достает фильтр по метаданным из параметров запроса
+func parseFilter(r *http.Request) (documents.Filter, error) {
+	params := r.URL.Query()
+	filter := documents.Filter{
+		Tag:    params.Get("tag"),
+		Author: params.Get("author"),
+		From:   params.Get("from"),
+		To:     params.Get("to"),
+	}
+
+	return filter, filter.Validate()
+}
+
+// writeJSON записывает ответ в формате JSON
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("write response error: %v", err)
+	}
+}
+
+// writeError записывает ответ с ошибкой
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// writeQueryError записывает ответ с ошибкой разбора запроса
+func writeQueryError(w http.ResponseWriter, err error) {
+	var parseErr *search.ParseError
+	if !errors.As(err, &parseErr) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusBadRequest, errorResponse{Error: parseErr.Error(), Position: &parseErr.Pos})
+}
@@ -2,18 +2,13 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/aaaton/golem/v4"
-	"github.com/aaaton/golem/v4/dicts/ru"
-	"github.com/bbalet/stopwords"
-	"github.com/bzick/tokenizer"
 	"log"
+	"oip-course/internal/config"
 	"os"
-	"regexp"
 	"slices"
-	"strings"
-	"unicode/utf8"
 )
 
 const (
@@ -22,10 +17,16 @@ const (
 	lemmasDir = "lemmas"
 )
 
-// Регулярное выражение, проверяющее, что запись состоит из русских букв
-var russianWordRegexp = regexp.MustCompile("^[А-ЯЁа-яё]+$")
-
 func main() {
+	profilePath := flag.String("profile", config.DefaultProfilePath, "path to site profile")
+	normalizer := flag.String("normalizer", "", "lemma normalizer overriding the profile: lemmatizer:<langs>, stemmer:ru or hybrid:<langs>")
+	groupsDir := flag.String("lemmas", lemmasDir, "output directory for lemma groupings")
+	flag.Parse()
+
+	profile, err := config.LoadSiteProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("load site profile error: %v", err)
+	}
 
 	// Создание директории для токенов
 	if err := os.MkdirAll(tokensDir, 0755); err != nil {
@@ -33,13 +34,11 @@ func main() {
 	}
 
 	// Создание директории для лемм
-	if err := os.MkdirAll(lemmasDir, 0755); err != nil {
+	if err := os.MkdirAll(*groupsDir, 0755); err != nil {
 		log.Fatalf("create lemmas directory error: %v", err)
 	}
 
-	parser := tokenizer.New()
-
-	lemmatizer, err := golem.New(ru.New())
+	analyzer, err := profile.NewAnalyzer(*normalizer)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -63,19 +62,9 @@ func main() {
 
 		tokens := make([]string, 0)
 
-		// Достаем контент страницы и заполняем массив tokens русскими словами, игнорируя стоп-слова
-		doc.Find("div.body div.mblock div.itemblock div.memo").Each(func(i int, s *goquery.Selection) {
-			tokensStream := parser.ParseString(s.Text())
-			for tokensStream.IsValid() {
-				token := strings.ToLower(tokensStream.CurrentToken().ValueString())
-				if isRussianWord(token) && strings.TrimSpace(stopwords.CleanString(token, "ru", false)) != "" && utf8.RuneCountInString(token) > 2 {
-					tokens = append(tokens, token)
-				}
-
-				tokensStream.GoNext()
-			}
-
-			tokensStream.Close()
+		// Достаем контент страницы и заполняем массив tokens словами, прошедшими фильтры анализатора
+		doc.Find(profile.ContentSelector).Each(func(i int, s *goquery.Selection) {
+			tokens = append(tokens, analyzer.Tokens(s.Text())...)
 		})
 
 		var pageNum int
@@ -92,8 +81,10 @@ func main() {
 
 		tokensWriter := bufio.NewWriter(tokensFile)
 
-		// Заполняем мапу, где ключ - лемма, значение - массив токенов
+		// Заполняем мапу, где ключ - лемма, значение - массив токенов. Леммы запоминаем в порядке первого появления,
+		// чтобы повторный запуск давал те же файлы
 		lemmasMap := make(map[string][]string)
+		lemmas := make([]string, 0)
 		for _, token := range tokens {
 			// Записываем токен в файл
 			_, err = fmt.Fprintf(tokensWriter, "%s\n", token)
@@ -101,9 +92,13 @@ func main() {
 				log.Printf("write to tokens file error: %v", err)
 			}
 
-			lemma := lemmatizer.Lemma(token)
+			lemma := analyzer.Lemma(token)
+			if lemma == "" {
+				continue
+			}
 			if _, ok := lemmasMap[lemma]; !ok {
 				lemmasMap[lemma] = make([]string, 0)
+				lemmas = append(lemmas, lemma)
 			}
 
 			if !slices.Contains(lemmasMap[lemma], token) {
@@ -112,7 +107,7 @@ func main() {
 		}
 
 		// Создание файла с леммами
-		lemmasFile, err := os.Create(fmt.Sprintf("%s/lemmas_%d.txt", lemmasDir, pageNum))
+		lemmasFile, err := os.Create(fmt.Sprintf("%s/lemmas_%d.txt", *groupsDir, pageNum))
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -120,9 +115,9 @@ func main() {
 		lemmasWriter := bufio.NewWriter(lemmasFile)
 
 		// Запись лемм и токенов в файл
-		for lemma, tokens := range lemmasMap {
+		for _, lemma := range lemmas {
 			lemmaStr := lemma + ":"
-			for _, token := range tokens {
+			for _, token := range lemmasMap[lemma] {
 				lemmaStr = lemmaStr + " " + token
 			}
 
@@ -140,8 +135,3 @@ func main() {
 	}
 
 }
-
-// Проверка, что слово слово состоит из русских букв
-func isRussianWord(word string) bool {
-	return russianWordRegexp.MatchString(word)
-}
@@ -2,14 +2,17 @@ module oip-course
 
 go 1.23.5
 
-require github.com/PuerkitoBio/goquery v1.10.2
+require (
+	github.com/PuerkitoBio/goquery v1.10.2
+	github.com/aaaton/golem/v4 v4.0.1
+	github.com/aaaton/golem/v4/dicts/en v1.0.1
+	github.com/aaaton/golem/v4/dicts/ru v0.0.0-20221121100719-34023a0c192d
+	github.com/bbalet/stopwords v1.0.0
+	github.com/bzick/tokenizer v1.4.10
+)
 
 require (
-	github.com/aaaton/golem/v4 v4.0.1 // indirect
-	github.com/aaaton/golem/v4/dicts/ru v0.0.0-20221121100719-34023a0c192d // indirect
 	github.com/andybalholm/cascadia v1.3.3 // indirect
-	github.com/bbalet/stopwords v1.0.0 // indirect
-	github.com/bzick/tokenizer v1.4.10 // indirect
 	golang.org/x/net v0.35.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 )
@@ -0,0 +1,277 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/bzick/tokenizer"
+)
+
+// Config описывает конвейер анализа: токенизатор и цепочку фильтров по названиям.
+// Фильтр записывается как "название" или "название:аргумент", например "min_length:3" или "lemmatizer:ru"
+type Config struct {
+	Tokenizer string   `json:"tokenizer"`
+	Filters   []string `json:"filters"`
+}
+
+// DefaultConfig конвейер по умолчанию: русские слова длиннее двух букв в нижнем регистре, без стоп-слов, приведенные к леммам
+var DefaultConfig = Config{
+	Tokenizer: "standard",
+	Filters:   []string{"lowercase", "script:russian", "min_length:3", "stopwords:ru", "lemmatizer:ru"},
+}
+
+// WithDefaults возвращает конфигурацию, в которой незаданные токенизатор и фильтры взяты из DefaultConfig
+func (c Config) WithDefaults() Config {
+	if c.Tokenizer == "" {
+		c.Tokenizer = DefaultConfig.Tokenizer
+	}
+	if c.Filters == nil {
+		c.Filters = DefaultConfig.Filters
+	}
+
+	return c
+}
+
+// WithNormalizer возвращает конфигурацию, в которой нормализатор (лемматизатор, стеммер или гибрид) заменен на заданный,
+// например "stemmer:ru". Если нормализатора в цепочке нет, он добавляется в конец
+func (c Config) WithNormalizer(spec string) (Config, error) {
+	name, _, _ := strings.Cut(spec, ":")
+	if !filters[name].normalizer {
+		return Config{}, fmt.Errorf("%q is not a normalizer, expected one of: lemmatizer, stemmer, hybrid", name)
+	}
+
+	c = c.WithDefaults()
+	replaced := false
+	result := make([]string, 0, len(c.Filters)+1)
+	for _, filter := range c.Filters {
+		name, _, _ := strings.Cut(filter, ":")
+		if filters[name].normalizer && !replaced {
+			filter, replaced = spec, true
+		}
+		result = append(result, filter)
+	}
+	if !replaced {
+		result = append(result, spec)
+	}
+	c.Filters = result
+
+	return c, nil
+}
+
+// Normalizer возвращает нормализатор из цепочки фильтров, например "lemmatizer:ru", или пустую строку, если его нет
+func (c Config) Normalizer() string {
+	for _, filter := range c.Filters {
+		if name, _, _ := strings.Cut(filter, ":"); filters[name].normalizer {
+			return filter
+		}
+	}
+
+	return ""
+}
+
+// String возвращает конфигурацию в виде "токенизатор | фильтр1 | фильтр2"
+func (c Config) String() string {
+	return strings.Join(append([]string{c.Tokenizer}, c.Filters...), " | ")
+}
+
+// Tokenizer разбивает текст на слова
+type Tokenizer interface {
+	Split(text string) []string
+}
+
+// tokenizers токенизаторы по названиям
+var tokenizers = map[string]func() Tokenizer{
+	"standard": func() Tokenizer { return standardTokenizer{parser: tokenizer.New()} },
+	"compound": func() Tokenizer { return compoundTokenizer{} },
+}
+
+// standardTokenizer разбивает текст на слова токенизатором bzick/tokenizer
+type standardTokenizer struct {
+	parser *tokenizer.Tokenizer
+}
+
+func (t standardTokenizer) Split(text string) []string {
+	words := make([]string, 0)
+
+	stream := t.parser.ParseString(text)
+	for stream.IsValid() {
+		words = append(words, stream.CurrentToken().ValueString())
+		stream.GoNext()
+	}
+	stream.Close()
+
+	return words
+}
+
+// compoundTokenizer разбивает текст на слова из букв и цифр, не разделяя слова через дефис ("ДНК-полимераза", "CRISPR-Cas9")
+// и десятичные дроби ("3,5", "1.5")
+type compoundTokenizer struct{}
+
+func (compoundTokenizer) Split(text string) []string {
+	words := make([]string, 0)
+	runes := []rune(text)
+
+	isWordRune := func(i int) bool {
+		return i >= 0 && i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]))
+	}
+
+	for i := 0; i < len(runes); {
+		if !isWordRune(i) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(runes) {
+			switch {
+			case isWordRune(i):
+				i++
+				continue
+			case strings.ContainsRune(hyphens, runes[i]) && isWordRune(i+1):
+				runes[i] = '-'
+				i++
+				continue
+			case isDecimalSeparator(runes, i):
+				i++
+				continue
+			}
+			break
+		}
+
+		words = append(words, string(runes[start:i]))
+	}
+
+	return words
+}
+
+// isDecimalSeparator проверяет, что точка или запятая в позиции i разделяет целую и дробную части числа.
+// В датах и номерах вида "29.01.2025" разделители не считаются десятичными
+func isDecimalSeparator(runes []rune, i int) bool {
+	isSeparator := func(i int) bool {
+		return i >= 0 && i < len(runes) && (runes[i] == '.' || runes[i] == ',')
+	}
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(runes) && unicode.IsDigit(runes[i])
+	}
+
+	if !isSeparator(i) || !isDigit(i-1) || !isDigit(i+1) {
+		return false
+	}
+
+	start := i - 1
+	for isDigit(start - 1) {
+		start--
+	}
+	end := i + 1
+	for isDigit(end + 1) {
+		end++
+	}
+
+	return !(isSeparator(start-1) && isDigit(start-2)) && !(isSeparator(end+1) && isDigit(end+2))
+}
+
+// hyphens дефисы, соединяющие части слова. В токене все они заменяются на "-"
+const hyphens = "-\u2010\u2011"
+
+// Analyzer конвейер анализа текста, общий для построения индекса и разбора запросов.
+// Фильтры до первого нормализатора (лемматизатора или стеммера) отбирают токены, нормализатор и фильтры после него
+// приводят токен к термину индекса
+type Analyzer struct {
+	config      Config
+	tokenizer   Tokenizer
+	tokenFilter []Filter // Фильтры токенов
+	termFilter  []Filter // Нормализатор и следующие за ним фильтры
+}
+
+// New собирает конвейер анализа по конфигурации
+func New(config Config) (*Analyzer, error) {
+	config = config.WithDefaults()
+
+	newTokenizer, ok := tokenizers[config.Tokenizer]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer %q", config.Tokenizer)
+	}
+
+	a := &Analyzer{config: config, tokenizer: newTokenizer()}
+
+	for _, spec := range config.Filters {
+		name, arg, _ := strings.Cut(spec, ":")
+
+		factory, ok := filters[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+
+		filter, err := factory.create(arg)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q: %w", spec, err)
+		}
+
+		if factory.normalizer || len(a.termFilter) > 0 {
+			a.termFilter = append(a.termFilter, filter)
+		} else {
+			a.tokenFilter = append(a.tokenFilter, filter)
+		}
+	}
+
+	return a, nil
+}
+
+// Config возвращает конфигурацию конвейера
+func (a *Analyzer) Config() Config {
+	return a.config
+}
+
+// Normalizer возвращает нормализатор конвейера, например "lemmatizer:ru", или пустую строку, если его нет
+func (a *Analyzer) Normalizer() string {
+	return a.config.Normalizer()
+}
+
+// Tokens разбивает текст на слова и пропускает их через фильтры токенов
+func (a *Analyzer) Tokens(text string) []string {
+	tokens := make([]string, 0)
+	for _, word := range a.tokenizer.Split(text) {
+		if token, ok := apply(a.tokenFilter, word); ok {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// Lemma приводит токен к термину индекса. Если фильтры после нормализатора отбросили токен, возвращается пустая строка
+func (a *Analyzer) Lemma(token string) string {
+	term, ok := apply(a.termFilter, token)
+	if !ok {
+		return ""
+	}
+
+	return term
+}
+
+// Lemmas разбивает текст на токены и приводит их к терминам индекса
+func (a *Analyzer) Lemmas(text string) []string {
+	tokens := a.Tokens(text)
+
+	lemmas := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if lemma := a.Lemma(token); lemma != "" {
+			lemmas = append(lemmas, lemma)
+		}
+	}
+
+	return lemmas
+}
+
+// apply пропускает токен через цепочку фильтров, false - токен отброшен
+func apply(chain []Filter, token string) (string, bool) {
+	for _, filter := range chain {
+		var ok bool
+		if token, ok = filter.Filter(token); !ok {
+			return "", false
+		}
+	}
+
+	return token, true
+}
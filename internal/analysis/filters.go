@@ -0,0 +1,321 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aaaton/golem/v4"
+	"github.com/aaaton/golem/v4/dicts/en"
+	"github.com/aaaton/golem/v4/dicts/ru"
+	"github.com/bbalet/stopwords"
+)
+
+// Filter звено конвейера анализа: преобразует токен или отбрасывает его, возвращая false
+type Filter interface {
+	Filter(token string) (string, bool)
+}
+
+// FilterFunc функция, реализующая Filter
+type FilterFunc func(token string) (string, bool)
+
+func (f FilterFunc) Filter(token string) (string, bool) {
+	return f(token)
+}
+
+// filterFactory создает фильтр по аргументу из конфигурации
+type filterFactory struct {
+	normalizer bool // Фильтр приводит токен к термину индекса (лемматизатор, стеммер)
+	create     func(arg string) (Filter, error)
+}
+
+// filters фильтры по названиям
+var filters = map[string]filterFactory{
+	"lowercase":  {create: newLowercaseFilter},
+	"yo":         {create: newYoFilter},
+	"script":     {create: newScriptFilter},
+	"min_length": {create: newMinLengthFilter},
+	"max_length": {create: newMaxLengthFilter},
+	"stopwords":  {create: newStopwordsFilter},
+	"lemmatizer": {normalizer: true, create: newLemmatizerFilter},
+	"stemmer":    {normalizer: true, create: newStemmerFilter},
+	"hybrid":     {normalizer: true, create: newHybridFilter},
+}
+
+// newLowercaseFilter приводит токен к нижнему регистру
+func newLowercaseFilter(arg string) (Filter, error) {
+	if arg != "" {
+		return nil, fmt.Errorf("no argument expected")
+	}
+
+	return FilterFunc(func(token string) (string, bool) {
+		return strings.ToLower(token), true
+	}), nil
+}
+
+// newYoFilter заменяет "ё" на "е": в текстах статей обычно пишется "е", а словарь лемматизатора знает формы с "ё"
+// ("чёрная" - "чёрный", но "черная" остается как есть). Перед нормализатором фильтр приводит к одному виду токены
+// текста и запроса, после нормализатора - леммы
+func newYoFilter(arg string) (Filter, error) {
+	if arg != "" {
+		return nil, fmt.Errorf("no argument expected")
+	}
+
+	replacer := strings.NewReplacer("ё", "е", "Ё", "Е")
+	return FilterFunc(func(token string) (string, bool) {
+		return replacer.Replace(token), true
+	}), nil
+}
+
+// scripts алфавиты, из букв которых может состоять токен
+var scripts = map[string]func(r rune) bool{
+	"russian": func(r rune) bool {
+		return r >= 'а' && r <= 'я' || r >= 'А' && r <= 'Я' || r == 'ё' || r == 'Ё'
+	},
+	"latin": func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	},
+	"digits": unicode.IsDigit,
+}
+
+// newScriptFilter оставляет токены, буквы и цифры которых принадлежат алфавитам, перечисленным через "+",
+// например "script:russian+latin+digits". Дефисы и десятичные разделители внутри токена допускаются
+func newScriptFilter(arg string) (Filter, error) {
+	var allowed []func(r rune) bool
+	for _, name := range strings.Split(arg, "+") {
+		script, ok := scripts[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown script %q", name)
+		}
+		allowed = append(allowed, script)
+	}
+
+	inScripts := func(r rune) bool {
+		for _, script := range allowed {
+			if script(r) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return FilterFunc(func(token string) (string, bool) {
+		if !strings.ContainsFunc(token, inScripts) {
+			return "", false
+		}
+
+		for _, r := range token {
+			if !inScripts(r) && !strings.ContainsRune("-.,", r) {
+				return "", false
+			}
+		}
+
+		return token, true
+	}), nil
+}
+
+// newMinLengthFilter оставляет токены не короче заданного кол-ва символов
+func newMinLengthFilter(arg string) (Filter, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("length must be a non-negative integer")
+	}
+
+	return FilterFunc(func(token string) (string, bool) {
+		return token, utf8.RuneCountInString(token) >= n
+	}), nil
+}
+
+// newMaxLengthFilter оставляет токены не длиннее заданного кол-ва символов
+func newMaxLengthFilter(arg string) (Filter, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("length must be a positive integer")
+	}
+
+	return FilterFunc(func(token string) (string, bool) {
+		return token, utf8.RuneCountInString(token) <= n
+	}), nil
+}
+
+// newStopwordsFilter отбрасывает стоп-слова языков с заданными через "+" кодами, например "stopwords:ru+en".
+// Слово через дефис отбрасывается, если все его части - стоп-слова ("из-за"). Числа стоп-словами не считаются
+func newStopwordsFilter(arg string) (Filter, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("language code is required")
+	}
+	languages := strings.Split(arg, "+")
+
+	return FilterFunc(func(token string) (string, bool) {
+		for _, part := range strings.Split(token, "-") {
+			if !isStopword(part, languages) {
+				return token, true
+			}
+		}
+
+		return "", false
+	}), nil
+}
+
+// isStopword проверяет, что слово - стоп-слово одного из языков
+func isStopword(word string, languages []string) bool {
+	if !strings.ContainsFunc(word, unicode.IsLetter) {
+		return false
+	}
+
+	for _, language := range languages {
+		if strings.TrimSpace(stopwords.CleanString(word, language, false)) == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// languages словари лемматизатора и стеммеры по кодам языков, а также алфавиты слов, которые они нормализуют
+var languages = map[string]struct {
+	script     string
+	dictionary func() golem.LanguagePack
+	stem       func(word string) string
+}{
+	"ru": {script: "russian", dictionary: func() golem.LanguagePack { return ru.New() }, stem: StemRussian},
+	"en": {script: "latin", dictionary: func() golem.LanguagePack { return en.New() }},
+}
+
+// newLemmatizerFilter приводит токен к лемме по словарям языков с заданными через "+" кодами, например "lemmatizer:ru+en".
+// Словарь выбирается по алфавиту слова, части слова через дефис приводятся к леммам по отдельности.
+// Слова, которых нет в словаре, и числа не меняются
+func newLemmatizerFilter(arg string) (Filter, error) {
+	return newNormalizer(arg, func(language string) (func(word string) string, error) {
+		lemmatizer, err := loadDictionary(language)
+		if err != nil {
+			return nil, err
+		}
+
+		return lemmatizer.Lemma, nil
+	})
+}
+
+// newStemmerFilter отсекает окончания стеммером Snowball языков с заданными через "+" кодами, например "stemmer:ru".
+// Части слова через дефис обрабатываются по отдельности
+func newStemmerFilter(arg string) (Filter, error) {
+	return newNormalizer(arg, func(language string) (func(word string) string, error) {
+		if stem := languages[language].stem; stem != nil {
+			return stem, nil
+		}
+
+		return nil, fmt.Errorf("no stemmer for language %q", language)
+	})
+}
+
+// newHybridFilter приводит к лемме слова, которые есть в словаре языка, остальные слова обрабатывает стеммером,
+// если он есть для языка, например "hybrid:ru+en". Части слова через дефис обрабатываются по отдельности
+func newHybridFilter(arg string) (Filter, error) {
+	return newNormalizer(arg, func(language string) (func(word string) string, error) {
+		lemmatizer, err := loadDictionary(language)
+		if err != nil {
+			return nil, err
+		}
+
+		stem := languages[language].stem
+		if stem == nil {
+			return lemmatizer.Lemma, nil
+		}
+
+		return func(word string) string {
+			if lemmatizer.InDict(word) {
+				return lemmatizer.Lemma(word)
+			}
+			return stem(word)
+		}, nil
+	})
+}
+
+// NewUnknownWordStemmer возвращает функцию, которая приводит слово, которого нет в словаре лемматизатора,
+// к основе стеммера языка. Для слов из словаря, слов через дефис и языков без стеммера возвращается пустая строка.
+// Языки задаются кодами через "+", как у лемматизатора
+func NewUnknownWordStemmer(arg string) (func(word string) string, error) {
+	var normalizers []scriptNormalizer
+	for _, language := range strings.Split(arg, "+") {
+		if _, ok := languages[language]; !ok {
+			return nil, fmt.Errorf("unknown language %q", language)
+		}
+
+		stem := languages[language].stem
+		if stem == nil {
+			continue
+		}
+
+		lemmatizer, err := loadDictionary(language)
+		if err != nil {
+			return nil, err
+		}
+
+		normalizers = append(normalizers, scriptNormalizer{
+			script: scripts[languages[language].script],
+			normalize: func(word string) string {
+				if lemmatizer.InDict(word) {
+					return ""
+				}
+				return stem(word)
+			},
+		})
+	}
+
+	return func(word string) string {
+		for _, normalizer := range normalizers {
+			if word != "" && strings.IndexFunc(word, func(r rune) bool { return !normalizer.script(r) }) == -1 {
+				return normalizer.normalize(word)
+			}
+		}
+		return ""
+	}, nil
+}
+
+// loadDictionary загружает словарь лемматизатора языка
+func loadDictionary(language string) (*golem.Lemmatizer, error) {
+	return golem.New(languages[language].dictionary())
+}
+
+// newNormalizer собирает фильтр из нормализаторов языков с заданными через "+" кодами
+func newNormalizer(arg string, normalizer func(language string) (func(word string) string, error)) (Filter, error) {
+	var normalizers []scriptNormalizer
+	for _, language := range strings.Split(arg, "+") {
+		if _, ok := languages[language]; !ok {
+			return nil, fmt.Errorf("unknown language %q", language)
+		}
+
+		normalize, err := normalizer(language)
+		if err != nil {
+			return nil, err
+		}
+		normalizers = append(normalizers, scriptNormalizer{script: scripts[languages[language].script], normalize: normalize})
+	}
+
+	return normalizeParts(normalizers), nil
+}
+
+// scriptNormalizer нормализует слова, состоящие из букв алфавита
+type scriptNormalizer struct {
+	script    func(r rune) bool
+	normalize func(word string) string
+}
+
+// normalizeParts возвращает фильтр, который нормализует каждую часть слова через дефис нормализатором ее алфавита
+func normalizeParts(normalizers []scriptNormalizer) Filter {
+	return FilterFunc(func(token string) (string, bool) {
+		parts := strings.Split(token, "-")
+		for i, part := range parts {
+			for _, normalizer := range normalizers {
+				if part != "" && strings.IndexFunc(part, func(r rune) bool { return !normalizer.script(r) }) == -1 {
+					parts[i] = normalizer.normalize(part)
+					break
+				}
+			}
+		}
+
+		return strings.Join(parts, "-"), true
+	})
+}
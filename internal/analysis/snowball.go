@@ -0,0 +1,150 @@
+package analysis
+
+import "strings"
+
+// Окончания русского стеммера Snowball (https://snowballstem.org/algorithms/russian/stemmer.html).
+// Окончания первой группы отсекаются, только если перед ними стоит "а" или "я"
+var (
+	perfectiveGerund1 = []string{"в", "вши", "вшись"}
+	perfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+
+	adjective = []string{
+		"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+
+	participle1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2 = []string{"ивш", "ывш", "ующ"}
+
+	reflexive = []string{"ся", "сь"}
+
+	verb1 = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	verb2 = []string{
+		"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+		"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю",
+	}
+
+	noun = []string{
+		"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+		"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я",
+	}
+
+	derivational = []string{"ост", "ость"}
+	superlative  = []string{"ейш", "ейше"}
+)
+
+// StemRussian отсекает от русского слова окончание и словообразовательные суффиксы по алгоритму Snowball
+func StemRussian(word string) string {
+	s := &stemmer{word: []rune(strings.ReplaceAll(word, "ё", "е"))}
+	s.regions()
+
+	// Шаг 1: деепричастие, иначе возвратная частица и прилагательное, глагол или существительное
+	if !s.cut(perfectiveGerund1, perfectiveGerund2, s.rv) {
+		s.cut(nil, reflexive, s.rv)
+		if s.cut(nil, adjective, s.rv) {
+			s.cut(participle1, participle2, s.rv)
+		} else if !s.cut(verb1, verb2, s.rv) {
+			s.cut(nil, noun, s.rv)
+		}
+	}
+
+	// Шаг 2: конечная "и"
+	s.cut(nil, []string{"и"}, s.rv)
+
+	// Шаг 3: словообразовательный суффикс в R2
+	s.cut(nil, derivational, s.r2)
+
+	// Шаг 4: двойная "н", превосходная степень или мягкий знак
+	switch {
+	case s.cut(nil, superlative, s.rv):
+		s.undouble()
+	case s.undouble():
+	default:
+		s.cut(nil, []string{"ь"}, s.rv)
+	}
+
+	return string(s.word)
+}
+
+// stemmer слово и начала его областей RV и R2 в символах
+type stemmer struct {
+	word []rune
+	rv   int // Область после первой гласной
+	r2   int // Область R1 внутри R1, R1 - область после первой согласной, следующей за гласной
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// regions находит начала областей RV и R2
+func (s *stemmer) regions() {
+	s.rv, s.r2 = len(s.word), len(s.word)
+
+	r1 := len(s.word)
+	for i, r := range s.word {
+		if isVowel(r) {
+			s.rv = i + 1
+			break
+		}
+	}
+	for i := 1; i < len(s.word); i++ {
+		if !isVowel(s.word[i]) && isVowel(s.word[i-1]) {
+			r1 = i + 1
+			break
+		}
+	}
+	for i := r1 + 1; i < len(s.word); i++ {
+		if !isVowel(s.word[i]) && isVowel(s.word[i-1]) {
+			s.r2 = i + 1
+			break
+		}
+	}
+}
+
+// cut отсекает самое длинное из окончаний обеих групп, целиком лежащее в области, начинающейся с позиции start.
+// Окончание группы preceded отсекается, только если перед ним в той же области стоит "а" или "я".
+// Более короткие окончания не пробуются, если самое длинное не подошло
+func (s *stemmer) cut(preceded, plain []string, start int) bool {
+	if start > len(s.word) {
+		return false
+	}
+
+	best, needsAYa := 0, false
+	region := string(s.word[start:])
+
+	for _, group := range []struct {
+		endings  []string
+		needsAYa bool
+	}{{preceded, true}, {plain, false}} {
+		for _, ending := range group.endings {
+			if n := len([]rune(ending)); n > best && strings.HasSuffix(region, ending) {
+				best, needsAYa = n, group.needsAYa
+			}
+		}
+	}
+	if best == 0 {
+		return false
+	}
+
+	end := len(s.word) - best
+	if needsAYa && (end-1 < start || s.word[end-1] != 'а' && s.word[end-1] != 'я') {
+		return false
+	}
+
+	s.word = s.word[:end]
+
+	return true
+}
+
+// undouble заменяет конечную двойную "н" в RV на одну
+func (s *stemmer) undouble() bool {
+	n := len(s.word)
+	if n-2 < s.rv || s.word[n-1] != 'н' || s.word[n-2] != 'н' {
+		return false
+	}
+
+	s.word = s.word[:n-1]
+
+	return true
+}
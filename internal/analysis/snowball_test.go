@@ -0,0 +1,89 @@
+package analysis
+
+import "testing"
+
+// Пары слово - основа из эталонного словаря Snowball (https://github.com/snowballstem/snowball-data, russian/voc.txt
+// и russian/output.txt), по несколько слов на каждый шаг алгоритма
+func TestStemRussian(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// Короткие слова и слова без окончаний
+		{"а", "а"},
+		{"в", "в"},
+		{"я", "я"},
+		{"он", "он"},
+		{"эти", "эт"},
+		{"август", "август"},
+
+		// Деепричастия
+		{"сказав", "сказа"},
+		{"задевши", "задевш"},
+		{"воспользовавшись", "воспользова"},
+		{"оглянувшись", "оглянувш"},
+
+		// Возвратная частица
+		{"берется", "берет"},
+		{"бросится", "брос"},
+		{"береглась", "берегл"},
+		{"варились", "вар"},
+		{"беспокойтесь", "беспокойт"},
+
+		// Прилагательные и причастия
+		{"бедную", "бедн"},
+		{"висячую", "висяч"},
+		{"белее", "бел"},
+		{"вызывающее", "вызыва"},
+		{"буржуазного", "буржуазн"},
+		{"аристократического", "аристократическ"},
+		{"нынешнему", "нынешн"},
+		{"гнилыми", "гнил"},
+		{"бывший", "бывш"},
+		{"следующий", "след"},
+		{"образованный", "образова"},
+
+		// Глаголы
+		{"будешь", "будеш"},
+		{"задираете", "задира"},
+		{"бродила", "брод"},
+		{"выскочила", "выскоч"},
+		{"берем", "бер"},
+
+		// Существительные
+		{"балалайками", "балалайк"},
+		{"волнами", "волн"},
+		{"диссертацией", "диссертац"},
+		{"выражениях", "выражен"},
+		{"бездействия", "бездейств"},
+		{"воспитания", "воспитан"},
+		{"волнением", "волнен"},
+		{"кровью", "кров"},
+		{"браслете", "браслет"},
+
+		// Словообразовательный суффикс в R2
+		{"безнадежность", "безнадежн"},
+		{"дерзость", "дерзост"},
+
+		// Превосходная степень, двойная "н" и мягкий знак
+		{"величайший", "величайш"},
+		{"наиприличнейшая", "наиприличн"},
+		{"прекраснейшая", "прекрасн"},
+		{"беднейшие", "бедн"},
+		{"безнравственно", "безнравствен"},
+		{"величественно", "величествен"},
+		{"длинный", "длин"},
+		{"жизнь", "жизн"},
+		{"аминь", "амин"},
+		{"окончательно", "окончательн"},
+
+		// "ё" приводится к "е"
+		{"берёт", "берет"},
+	}
+
+	for _, tt := range tests {
+		if got := StemRussian(tt.word); got != tt.want {
+			t.Errorf("StemRussian(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"oip-course/internal/analysis"
+	"os"
+	"strings"
+)
+
+// DefaultProfilePath путь к профилю сайта по умолчанию
+const DefaultProfilePath = "configs/elementy.json"
+
+// SiteProfile описывает индексируемый сайт: где брать ссылки на статьи и где на странице статьи лежит ее текст
+type SiteProfile struct {
+	Name             string `json:"name"`
+	ListingURL       string `json:"listing_url"`        // Шаблон URL страницы ленты, %d заменяется на номер страницы ленты
+	FirstListingPage int    `json:"first_listing_page"` // Номер первой страницы ленты
+	LinkSelector     string `json:"link_selector"`      // CSS селектор ссылок на статьи в ленте
+	ContentSelector  string `json:"content_selector"`   // CSS селектор блока с текстом статьи
+	PageLimit        int    `json:"page_limit"`         // Кол-во статей, которые берутся из ленты
+
+	Metadata MetadataSelectors `json:"metadata"` // Где на странице статьи лежат ее метаданные
+
+	Analyzer analysis.Config `json:"analyzer"` // Конвейер анализа текста, незаданные части берутся из analysis.DefaultConfig
+}
+
+// FieldSelector описывает, откуда взять значение поля метаданных
+type FieldSelector struct {
+	Selector string `json:"selector"`         // CSS селектор элемента
+	Attr     string `json:"attr,omitempty"`   // Атрибут элемента со значением, по умолчанию берется текст элемента
+	Layout   string `json:"layout,omitempty"` // Формат даты в нотации пакета time, только для даты публикации
+}
+
+// MetadataSelectors селекторы метаданных статьи. Поля с пустым селектором не извлекаются
+type MetadataSelectors struct {
+	Title  FieldSelector `json:"title"`
+	Date   FieldSelector `json:"date"`
+	Author FieldSelector `json:"author"`
+	Tags   FieldSelector `json:"tags"`
+	Lead   FieldSelector `json:"lead"`
+}
+
+// LoadSiteProfile загружает профиль сайта из JSON файла и проверяет обязательные поля
+func LoadSiteProfile(path string) (*SiteProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile SiteProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("decode site profile %s: %w", path, err)
+	}
+
+	if err := profile.validate(); err != nil {
+		return nil, fmt.Errorf("site profile %s: %w", path, err)
+	}
+
+	profile.Analyzer = profile.Analyzer.WithDefaults()
+
+	return &profile, nil
+}
+
+// NewAnalyzer собирает конвейер анализа профиля. Непустой normalizer, например "stemmer:ru", заменяет нормализатор профиля
+func (p *SiteProfile) NewAnalyzer(normalizer string) (*analysis.Analyzer, error) {
+	analyzerConfig := p.Analyzer
+	if normalizer != "" {
+		var err error
+		if analyzerConfig, err = analyzerConfig.WithNormalizer(normalizer); err != nil {
+			return nil, err
+		}
+	}
+
+	return analysis.New(analyzerConfig)
+}
+
+// ListingPageURL возвращает URL страницы ленты с заданным номером
+func (p *SiteProfile) ListingPageURL(page int) string {
+	return fmt.Sprintf(p.ListingURL, page)
+}
+
+func (p *SiteProfile) validate() error {
+	switch {
+	case !strings.Contains(p.ListingURL, "%d"):
+		return fmt.Errorf("listing_url must contain %%d placeholder for listing page number")
+	case p.LinkSelector == "":
+		return fmt.Errorf("link_selector is required")
+	case p.ContentSelector == "":
+		return fmt.Errorf("content_selector is required")
+	case p.PageLimit < 1:
+		return fmt.Errorf("page_limit must be positive")
+	case p.Metadata.Date.Selector != "" && p.Metadata.Date.Layout == "":
+		return fmt.Errorf("metadata.date.layout is required when metadata.date.selector is set")
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package documents
+
+import (
+	"encoding/json"
+	"errors"
+	"oip-course/internal/config"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultStorePath путь к хранилищу метаданных документов по умолчанию
+const DefaultStorePath = "documents.json"
+
+// dateFormat формат, в котором хранится дата публикации
+const dateFormat = "2006-01-02"
+
+// Document метаданные статьи
+type Document struct {
+	Page   int      `json:"page"`
+	URL    string   `json:"url"`
+	Title  string   `json:"title"`
+	Date   string   `json:"date,omitempty"` // Дата публикации в формате ГГГГ-ММ-ДД
+	Author string   `json:"author,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Lead   string   `json:"lead,omitempty"` // Лид - первый абзац статьи
+}
+
+// Extract извлекает метаданные статьи из страницы по селекторам профиля сайта
+func Extract(doc *goquery.Document, selectors config.MetadataSelectors) *Document {
+	document := &Document{
+		Title:  first(doc, selectors.Title),
+		Author: first(doc, selectors.Author),
+		Tags:   all(doc, selectors.Tags),
+		Lead:   first(doc, selectors.Lead),
+	}
+
+	// Дату приводим к единому формату, чтобы по ней можно было фильтровать
+	if date := first(doc, selectors.Date); date != "" {
+		if t, err := time.Parse(selectors.Date.Layout, date); err == nil {
+			document.Date = t.Format(dateFormat)
+		}
+	}
+
+	// Без селектора заголовка берем заголовок HTML документа
+	if document.Title == "" {
+		document.Title = normalizeSpace(doc.Find("title").First().Text())
+	}
+
+	return document
+}
+
+// first возвращает первое непустое значение поля
+func first(doc *goquery.Document, field config.FieldSelector) string {
+	values := all(doc, field)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// all возвращает все непустые различные значения поля в порядке следования на странице
+func all(doc *goquery.Document, field config.FieldSelector) []string {
+	if field.Selector == "" {
+		return nil
+	}
+
+	var values []string
+	doc.Find(field.Selector).Each(func(i int, s *goquery.Selection) {
+		value := s.Text()
+		if field.Attr != "" {
+			value, _ = s.Attr(field.Attr)
+		}
+
+		value = normalizeSpace(value)
+		if value != "" && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	})
+
+	return values
+}
+
+// normalizeSpace схлопывает пробельные символы в один пробел
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Store хранилище метаданных документов, ключ - номер страницы
+type Store struct {
+	documents map[int]*Document
+}
+
+func NewStore() *Store {
+	return &Store{
+		documents: make(map[int]*Document),
+	}
+}
+
+// LoadStore загружает хранилище из JSON файла. Если файла нет, возвращается пустое хранилище
+func LoadStore(filename string) (*Store, error) {
+	store := NewStore()
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &store.documents); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Save атомарно записывает хранилище в JSON файл
+func (s *Store) Save(filename string) error {
+	data, err := json.MarshalIndent(s.documents, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// Put добавляет или заменяет метаданные документа
+func (s *Store) Put(document *Document) {
+	s.documents[document.Page] = document
+}
+
+// Get возвращает метаданные документа по номеру страницы
+func (s *Store) Get(page int) (*Document, bool) {
+	document, ok := s.documents[page]
+	return document, ok
+}
+
+// Filter условия отбора документов. Пустые поля не ограничивают выборку
+type Filter struct {
+	Tag    string // Рубрика, без учета регистра
+	Author string // Автор, без учета регистра
+	From   string // Минимальная дата публикации в формате ГГГГ-ММ-ДД
+	To     string // Максимальная дата публикации в формате ГГГГ-ММ-ДД
+}
+
+// IsEmpty проверяет, что фильтр ничего не ограничивает
+func (f Filter) IsEmpty() bool {
+	return f == Filter{}
+}
+
+// Validate проверяет формат дат фильтра
+func (f Filter) Validate() error {
+	for _, date := range []string{f.From, f.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(dateFormat, date); err != nil {
+			return errors.New("dates must be in YYYY-MM-DD format")
+		}
+	}
+
+	return nil
+}
+
+// Match проверяет, подходит ли документ под фильтр
+func (f Filter) Match(document *Document) bool {
+	if f.Tag != "" && !slices.ContainsFunc(document.Tags, func(tag string) bool {
+		return strings.EqualFold(tag, f.Tag)
+	}) {
+		return false
+	}
+
+	if f.Author != "" && !strings.EqualFold(document.Author, f.Author) {
+		return false
+	}
+
+	// Даты в формате ГГГГ-ММ-ДД можно сравнивать как строки
+	if f.From != "" && (document.Date == "" || document.Date < f.From) {
+		return false
+	}
+	if f.To != "" && (document.Date == "" || document.Date > f.To) {
+		return false
+	}
+
+	return true
+}
+
+// Matches проверяет, подходит ли документ страницы под фильтр.
+// Страницы без метаданных не подходят, если фильтр не пустой
+func (s *Store) Matches(page int, filter Filter) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+
+	document, ok := s.documents[page]
+	return ok && filter.Match(document)
+}
+
+// FilterPages оставляет страницы, документы которых подходят под фильтр
+func (s *Store) FilterPages(pages []int, filter Filter) []int {
+	if filter.IsEmpty() {
+		return pages
+	}
+
+	var result []int
+	for _, page := range pages {
+		if s.Matches(page, filter) {
+			result = append(result, page)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TermDictionary отсортированный словарь терминов поля. Для поиска по окончанию хранятся также
+// отсортированные перевернутые термины, поэтому и префикс, и суффикс ищутся бинарным поиском
+type TermDictionary struct {
+	terms    []string
+	reversed []string
+}
+
+// NewTermDictionary строит словарь из терминов в любом порядке
+func NewTermDictionary(terms []string) *TermDictionary {
+	d := &TermDictionary{
+		terms:    append([]string(nil), terms...),
+		reversed: make([]string, 0, len(terms)),
+	}
+	sort.Strings(d.terms)
+
+	for _, term := range d.terms {
+		d.reversed = append(d.reversed, reverse(term))
+	}
+	sort.Strings(d.reversed)
+
+	return d
+}
+
+// Len возвращает кол-во терминов словаря
+func (d *TermDictionary) Len() int {
+	return len(d.terms)
+}
+
+// Terms возвращает отсортированные термины словаря. Срез нельзя изменять
+func (d *TermDictionary) Terms() []string {
+	return d.terms
+}
+
+// Prefix возвращает отсортированные термины, начинающиеся с prefix
+func (d *TermDictionary) Prefix(prefix string) []string {
+	return prefixRange(d.terms, prefix)
+}
+
+// Suffix возвращает отсортированные термины, оканчивающиеся на suffix
+func (d *TermDictionary) Suffix(suffix string) []string {
+	reversed := prefixRange(d.reversed, reverse(suffix))
+
+	terms := make([]string, 0, len(reversed))
+	for _, term := range reversed {
+		terms = append(terms, reverse(term))
+	}
+	sort.Strings(terms)
+
+	return terms
+}
+
+// Wildcard возвращает отсортированные термины, подходящие под шаблон, в котором "*" - любое кол-во символов.
+// Кандидаты берутся по началу шаблона до первой "*", если оно есть, иначе по окончанию после последней "*",
+// и только шаблон вида "*ген*" проверяется на всех терминах словаря
+func (d *TermDictionary) Wildcard(pattern string) []string {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		if i := sort.SearchStrings(d.terms, pattern); i < len(d.terms) && d.terms[i] == pattern {
+			return []string{pattern}
+		}
+		return nil
+	}
+
+	var candidates []string
+	switch first, last := parts[0], parts[len(parts)-1]; {
+	case first != "":
+		candidates = d.Prefix(first)
+	case last != "":
+		candidates = d.Suffix(last)
+	default:
+		candidates = d.terms
+	}
+
+	var terms []string
+	for _, term := range candidates {
+		if matchWildcard(parts, term) {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// prefixRange возвращает диапазон отсортированного среза, элементы которого начинаются с prefix
+func prefixRange(sorted []string, prefix string) []string {
+	start := sort.SearchStrings(sorted, prefix)
+
+	end := start
+	for end < len(sorted) && strings.HasPrefix(sorted[end], prefix) {
+		end++
+	}
+
+	return sorted[start:end]
+}
+
+// matchWildcard проверяет термин по частям шаблона между "*": первая часть - начало термина, последняя - окончание,
+// остальные должны встречаться между ними по порядку
+func matchWildcard(parts []string, term string) bool {
+	first, last := parts[0], parts[len(parts)-1]
+	if len(term) < len(first)+len(last) || !strings.HasPrefix(term, first) || !strings.HasSuffix(term, last) {
+		return false
+	}
+
+	middle := term[len(first) : len(term)-len(last)]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(middle, part)
+		if i < 0 {
+			return false
+		}
+		middle = middle[i+len(part):]
+	}
+
+	return true
+}
+
+// reverse переворачивает строку посимвольно
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
+
+// DictionaryCache словари терминов полей индекса, которые строятся при первом обращении.
+// Безопасен для одновременного использования
+type DictionaryCache struct {
+	mu           sync.Mutex
+	dictionaries map[string]*TermDictionary
+}
+
+// Get возвращает словарь поля, строя его из terms, если его еще нет
+func (c *DictionaryCache) Get(field string, terms func() []string) *TermDictionary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if dictionary, ok := c.dictionaries[field]; ok {
+		return dictionary
+	}
+
+	if c.dictionaries == nil {
+		c.dictionaries = make(map[string]*TermDictionary)
+	}
+	dictionary := NewTermDictionary(terms())
+	c.dictionaries[field] = dictionary
+
+	return dictionary
+}
+
+// Reset сбрасывает словарь поля, например после добавления в поле нового термина
+func (c *DictionaryCache) Reset(field string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.dictionaries, field)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"math/rand"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestWildcard(t *testing.T) {
+	dictionary := NewTermDictionary([]string{"ген", "гена", "геном", "генетика", "эпигенетика", "биология", "генетик", "кен", "агент"})
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"ген", []string{"ген"}},
+		{"гены", nil},
+		{"ген*", []string{"ген", "гена", "генетик", "генетика", "геном"}},
+		{"*ген", []string{"ген"}},
+		{"*тика", []string{"генетика", "эпигенетика"}},
+		{"*ген*", []string{"агент", "ген", "гена", "генетик", "генетика", "геном", "эпигенетика"}},
+		{"г*н", []string{"ген"}},
+		{"ген*ка", []string{"генетика"}},
+		{"*ген*ка", []string{"генетика", "эпигенетика"}},
+		{"г*е*а", []string{"гена", "генетика"}},
+		{"ген*ген", nil}, // Начало и окончание не могут перекрываться
+		{"*", []string{"агент", "биология", "ген", "гена", "генетик", "генетика", "геном", "кен", "эпигенетика"}},
+		{"**", []string{"агент", "биология", "ген", "гена", "генетик", "генетика", "геном", "кен", "эпигенетика"}},
+		{"х*", nil},
+	}
+
+	for _, tt := range tests {
+		if got := dictionary.Wildcard(tt.pattern); !slices.Equal(got, tt.want) {
+			t.Errorf("Wildcard(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []rune("агенкё")
+
+	terms := make([]string, 2_000)
+	for i := range terms {
+		terms[i] = randomWord(rng, alphabet, 8)
+	}
+	// Термины индекса не повторяются
+	slices.Sort(terms)
+	dictionary := NewTermDictionary(slices.Compact(terms))
+
+	// Шаблоны из букв и "*" сверяются с path.Match, у которого "*" означает то же самое
+	patternAlphabet := append(alphabet, '*', '*')
+	for range 500 {
+		pattern := randomWord(rng, patternAlphabet, 5)
+
+		var want []string
+		for _, term := range dictionary.Terms() {
+			if ok, _ := path.Match(pattern, term); ok {
+				want = append(want, term)
+			}
+		}
+
+		if got := dictionary.Wildcard(pattern); !slices.Equal(got, want) {
+			t.Fatalf("Wildcard(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestSuffix(t *testing.T) {
+	dictionary := NewTermDictionary([]string{"генетика", "эпигенетика", "физика", "ген"})
+
+	if got, want := dictionary.Suffix("тика"), []string{"генетика", "эпигенетика"}; !slices.Equal(got, want) {
+		t.Errorf("Suffix(тика) = %v, want %v", got, want)
+	}
+	if got, want := dictionary.Suffix("ика"), []string{"генетика", "физика", "эпигенетика"}; !slices.Equal(got, want) {
+		t.Errorf("Suffix(ика) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"oip-course/internal/search"
+	"sort"
+)
+
+// DefaultMaxExpansions наибольшее кол-во лемм, до которого по умолчанию раскрывается шаблон запроса
+const DefaultMaxExpansions = 256
+
+// ErrTooManyExpansions шаблон запроса подходит под большее кол-во лемм, чем разрешено
+var ErrTooManyExpansions = errors.New("too many term expansions")
+
+// EvaluateOptions настройки вычисления булевого запроса
+type EvaluateOptions struct {
+	MaxExpansions int        // Наибольшее кол-во лемм, до которого раскрывается шаблон или нечеткий поиск, 0 - DefaultMaxExpansions
+	TypoTolerance bool       // Слова, которых нет в индексе, ищутся нечетко с расстоянием AutoFuzzyDistance
+	Forms         *WordForms // Слова и фразы ищутся по всем формам слова, nil - только по лемме из запроса
+}
+
+// DefaultEvaluateOptions настройки вычисления по умолчанию
+var DefaultEvaluateOptions = EvaluateOptions{MaxExpansions: DefaultMaxExpansions}
+
+// postings результат вычисления узла запроса: сжатый список страниц и,
+// для слов, фраз, шаблонов и оператора близости, позиции совпадений на каждой странице
+type postings struct {
+	pages     *PostingsList
+	positions map[int][]int
+}
+
+// Evaluate вычисляет булевый запрос по индексу и возвращает отсортированный список найденных страниц.
+// Ошибка возвращается, если шаблон запроса раскрывается в большее кол-во лемм, чем options.MaxExpansions.
+// Нечеткий поиск при превышении MaxExpansions ограничивается ближайшими леммами
+func Evaluate(index Index, q search.Query, options EvaluateOptions) ([]int, error) {
+	if options.MaxExpansions <= 0 {
+		options.MaxExpansions = DefaultMaxExpansions
+	}
+
+	e := &evaluator{index: index, options: options, allPages: NewPostingsList(index.AllPages())}
+
+	result, err := e.evaluate(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.pages.Pages(), nil
+}
+
+// evaluator вычисляет узлы запроса по индексу
+type evaluator struct {
+	index    Index
+	options  EvaluateOptions
+	allPages *PostingsList
+}
+
+func (e *evaluator) evaluate(q search.Query) (postings, error) {
+	switch q := q.(type) {
+	case search.Term:
+		result := e.term(search.QueryField(q.Field), q.Lemma)
+		if e.options.TypoTolerance && result.pages.Len() == 0 {
+			return e.fuzzy(search.QueryField(q.Field), q.Lemma, AutoFuzzyDistance(q.Lemma)), nil
+		}
+		return result, nil
+	case search.Phrase:
+		return e.phrase(search.QueryField(q.Field), q.Lemmas), nil
+	case search.Wildcard:
+		return e.wildcard(q)
+	case search.Fuzzy:
+		return e.fuzzy(search.QueryField(q.Field), q.Lemma, q.Distance), nil
+	case search.Near:
+		return e.binary(q.Left, q.Right, func(left, right postings) postings {
+			return near(left, right, q.Distance)
+		})
+	case search.And:
+		return e.binary(q.Left, q.Right, func(left, right postings) postings {
+			return postings{pages: Intersect(left.pages, right.pages)}
+		})
+	case search.Or:
+		return e.binary(q.Left, q.Right, func(left, right postings) postings {
+			return postings{pages: Union(left.pages, right.pages)}
+		})
+	case search.Not:
+		operand, err := e.evaluate(q.Operand)
+		if err != nil {
+			return postings{}, err
+		}
+		return postings{pages: Difference(e.allPages, operand.pages)}, nil
+	default:
+		return postings{}, nil
+	}
+}
+
+// binary вычисляет оба операнда и объединяет их результаты функцией combine
+func (e *evaluator) binary(left, right search.Query, combine func(left, right postings) postings) (postings, error) {
+	l, err := e.evaluate(left)
+	if err != nil {
+		return postings{}, err
+	}
+
+	r, err := e.evaluate(right)
+	if err != nil {
+		return postings{}, err
+	}
+
+	return combine(l, r), nil
+}
+
+// term возвращает страницы и позиции леммы в поле, а если заданы формы слов - всех форм слова леммы
+func (e *evaluator) term(field string, lemma string) postings {
+	if e.options.Forms == nil {
+		return term(e.index, field, lemma)
+	}
+
+	return union(e.index, field, e.options.Forms.Expand(lemma))
+}
+
+// wildcard раскрывает шаблон в леммы поля по словарю индекса и объединяет их страницы и позиции
+func (e *evaluator) wildcard(q search.Wildcard) (postings, error) {
+	field := search.QueryField(q.Field)
+
+	lemmas := e.index.Dictionary(field).Wildcard(q.Pattern)
+	if len(lemmas) > e.options.MaxExpansions {
+		return postings{}, fmt.Errorf("%w: %q matches %d terms, the limit is %d", ErrTooManyExpansions, q.Pattern, len(lemmas), e.options.MaxExpansions)
+	}
+
+	return union(e.index, field, lemmas), nil
+}
+
+// fuzzy объединяет страницы и позиции лемм поля на расстоянии Левенштейна не больше distance от lemma.
+// Если таких лемм больше MaxExpansions, берутся ближайшие
+func (e *evaluator) fuzzy(field string, lemma string, distance int) postings {
+	matches := e.index.Dictionary(field).Fuzzy(lemma, distance)
+
+	lemmas := make([]string, 0, min(len(matches), e.options.MaxExpansions))
+	for _, match := range matches[:min(len(matches), e.options.MaxExpansions)] {
+		lemmas = append(lemmas, match.Term)
+	}
+
+	return union(e.index, field, lemmas)
+}
+
+// union возвращает страницы, на которых встречается хотя бы одна из лемм поля, и позиции всех этих лемм
+func union(index Index, field string, lemmas []string) postings {
+	positions := make(map[int][]int)
+	for _, lemma := range lemmas {
+		for page, lemmaPositions := range index.Postings(field, lemma) {
+			positions[page] = append(positions[page], lemmaPositions...)
+		}
+	}
+
+	pages := make([]int, 0, len(positions))
+	for page := range positions {
+		sort.Ints(positions[page])
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	return postings{pages: NewPostingsList(pages), positions: positions}
+}
+
+// term возвращает страницы и позиции леммы в поле
+func term(index Index, field string, lemma string) postings {
+	positions := index.Postings(field, lemma)
+
+	pages := make([]int, 0, len(positions))
+	for page := range positions {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	return postings{pages: NewPostingsList(pages), positions: positions}
+}
+
+// phrase возвращает страницы и позиции начала фразы, леммы которой идут в поле страницы подряд
+func (e *evaluator) phrase(field string, lemmas []string) postings {
+	result := e.term(field, lemmas[0])
+
+	for offset, lemma := range lemmas[1:] {
+		next := e.term(field, lemma)
+
+		pages := &PostingsList{}
+		positions := make(map[int][]int)
+		for _, page := range Intersect(result.pages, next.pages).Pages() {
+			// Позиция следующей леммы должна быть сдвинута относительно начала фразы на offset+1
+			nextPositions := make(map[int]bool)
+			for _, position := range next.positions[page] {
+				nextPositions[position] = true
+			}
+
+			for _, start := range result.positions[page] {
+				if nextPositions[start+offset+1] {
+					positions[page] = append(positions[page], start)
+				}
+			}
+
+			if len(positions[page]) > 0 {
+				pages.add(page)
+			}
+		}
+
+		result = postings{pages: pages, positions: positions}
+	}
+
+	return result
+}
+
+// near возвращает страницы, на которых вхождения операндов находятся не дальше distance слов друг от друга.
+// Позициями результата становятся позиции совпавших вхождений обоих операндов
+func near(left, right postings, distance int) postings {
+	pages := &PostingsList{}
+	positions := make(map[int][]int)
+
+	for _, page := range Intersect(left.pages, right.pages).Pages() {
+		matched := make(map[int]bool)
+		for _, l := range left.positions[page] {
+			for _, r := range right.positions[page] {
+				if abs(l-r) <= distance {
+					matched[l] = true
+					matched[r] = true
+				}
+			}
+		}
+
+		if len(matched) == 0 {
+			continue
+		}
+
+		for position := range matched {
+			positions[page] = append(positions[page], position)
+		}
+		sort.Ints(positions[page])
+		pages.add(page)
+	}
+
+	return postings{pages: pages, positions: positions}
+}
+
+// abs возвращает модуль числа
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"oip-course/internal/search"
+	"slices"
+	"testing"
+)
+
+func TestEvaluateMaxExpansions(t *testing.T) {
+	index := NewInvertedIndex(make(map[string]map[string]map[int][]int), make(map[string]map[int]int))
+	index.Add(FieldBody, "ген", 1, 0)
+	index.Add(FieldBody, "генетика", 2, 0)
+	index.Add(FieldBody, "геном", 3, 0)
+	index.Add(FieldBody, "клетка", 4, 0)
+
+	query := search.Wildcard{Pattern: "ген*"}
+
+	tests := []struct {
+		name    string
+		options EvaluateOptions
+		want    []int
+		wantErr error
+	}{
+		{"zero options", EvaluateOptions{}, []int{1, 2, 3}, nil},
+		{"negative", EvaluateOptions{MaxExpansions: -1}, []int{1, 2, 3}, nil},
+		{"defaults", DefaultEvaluateOptions, []int{1, 2, 3}, nil},
+		{"exact limit", EvaluateOptions{MaxExpansions: 3}, []int{1, 2, 3}, nil},
+		{"over limit", EvaluateOptions{MaxExpansions: 2}, nil, ErrTooManyExpansions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(index, query, tt.options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Evaluate error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Evaluate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"slices"
+	"sort"
+)
+
+// WordForms группы лемм индекса, которые являются формами одного слова. Например, лемматизатор оставляет
+// незнакомые словарю слова без изменений, и "абиогенез", "абиогенеза" и "абиогенезе" попадают в индекс
+// отдельными леммами, а стеммер объединяет эти токены в одну группу
+type WordForms struct {
+	forms map[string][]string // Лемма индекса -> отсортированные леммы ее группы, включая ее саму
+
+	// Для слов запроса, которых нет в индексе, группа ищется по ключу: normalize приводит слово к ключу группы
+	groups    map[string][]string
+	normalize func(word string) string
+}
+
+// NewWordForms строит группы лемм индекса по группировке токенов: группа -> токены, которые к ней приводятся.
+// lemma возвращает лемму индекса токена, пустую - если токен не индексируется
+func NewWordForms(groups map[string][]string, lemma func(token string) string) *WordForms {
+	f := &WordForms{forms: make(map[string][]string)}
+
+	for _, tokens := range groups {
+		lemmas := make(map[string]bool)
+		for _, token := range tokens {
+			if l := lemma(token); l != "" {
+				lemmas[l] = true
+			}
+		}
+
+		// Группа из одной леммы ничего не добавляет к поиску
+		if len(lemmas) < 2 {
+			continue
+		}
+
+		for l := range lemmas {
+			for other := range lemmas {
+				f.add(l, other)
+			}
+		}
+	}
+
+	for l := range f.forms {
+		sort.Strings(f.forms[l])
+	}
+
+	return f
+}
+
+// IndexWordForms группирует термины всех полей индекса, которые normalize приводит к одному ключу.
+// Пустой ключ исключает термин из групп. Слово запроса, которого нет в индексе, раскрывается в группу своего ключа
+func IndexWordForms(index Index, normalize func(term string) string) *WordForms {
+	groups := make(map[string][]string)
+	for _, field := range Fields {
+		for _, term := range index.Dictionary(field).Terms() {
+			if key := normalize(term); key != "" {
+				groups[key] = append(groups[key], term)
+			}
+		}
+	}
+
+	f := NewWordForms(groups, func(term string) string { return term })
+	f.groups, f.normalize = groups, normalize
+	for key, terms := range groups {
+		sort.Strings(terms)
+		groups[key] = slices.Compact(terms)
+	}
+
+	return f
+}
+
+// add добавляет form в группу леммы, если ее там еще нет
+func (f *WordForms) add(lemma string, form string) {
+	for _, existing := range f.forms[lemma] {
+		if existing == form {
+			return
+		}
+	}
+
+	f.forms[lemma] = append(f.forms[lemma], form)
+}
+
+// Expand возвращает леммы индекса, которые являются формами того же слова, что и lemma, включая ее саму
+func (f *WordForms) Expand(lemma string) []string {
+	if forms, ok := f.forms[lemma]; ok {
+		return forms
+	}
+
+	if f.normalize != nil {
+		if key := f.normalize(lemma); key != "" {
+			if group, ok := f.groups[key]; ok {
+				return group
+			}
+		}
+	}
+
+	return []string{lemma}
+}
+
+// Len возвращает кол-во лемм индекса, у которых есть другие формы
+func (f *WordForms) Len() int {
+	return len(f.forms)
+}
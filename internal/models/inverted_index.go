@@ -1,20 +1,182 @@
 package models
 
+import (
+	"math"
+	"sort"
+)
+
+// Поля документа, для каждого из которых строятся отдельные списки вхождений
+const (
+	FieldTitle = "title" // Заголовок статьи
+	FieldLead  = "lead"  // Лид статьи
+	FieldBody  = "body"  // Текст статьи
+	FieldTags  = "tags"  // Рубрики статьи
+)
+
+// Fields список полей документа
+var Fields = []string{FieldTitle, FieldLead, FieldBody, FieldTags}
+
+// Index источник списков вхождений лемм для поиска. Реализуется индексом в памяти и бинарным сегментом
+type Index interface {
+	// Postings возвращает позиции леммы в поле по номерам страниц
+	Postings(field string, lemma string) map[int][]int
+	// Terms возвращает отсортированный список лемм поля
+	Terms(field string) []string
+	// AllPages возвращает отсортированный список всех страниц индекса
+	AllPages() []int
+	// DocLength возвращает кол-во токенов в поле страницы
+	DocLength(field string, page int) int
+	// Dictionary возвращает отсортированный словарь лемм поля для поиска по шаблону
+	Dictionary(field string) *TermDictionary
+	// Metadata возвращает заголовок индекса
+	Metadata() Metadata
+}
+
 type InvertedIndex struct {
-	index map[string][]int
+	index    map[string]map[string]map[int][]int // поле -> лемма -> номер страницы -> позиции токенов леммы в поле
+	lengths  map[string]map[int]int              // поле -> номер страницы -> кол-во токенов в поле
+	metadata Metadata
+
+	dictionaries DictionaryCache // Словари лемм полей, строятся при первом поиске по шаблону
 }
 
-func NewInvertedIndex(index map[string][]int) *InvertedIndex {
+func NewInvertedIndex(index map[string]map[string]map[int][]int, lengths map[string]map[int]int) *InvertedIndex {
 	return &InvertedIndex{
-		index: index,
+		index:   index,
+		lengths: lengths,
 	}
 }
 
-// Add добавляет для леммы номер страницы, в котором эта лемма встречается
-func (ii *InvertedIndex) Add(lemma string, page int) {
-	ii.index[lemma] = append(ii.index[lemma], page)
+// Add добавляет для леммы номер страницы и позицию токена в поле страницы, в котором эта лемма встречается
+func (ii *InvertedIndex) Add(field string, lemma string, page int, position int) {
+	if _, ok := ii.index[field]; !ok {
+		ii.index[field] = make(map[string]map[int][]int)
+	}
+	if _, ok := ii.index[field][lemma]; !ok {
+		ii.index[field][lemma] = make(map[int][]int)
+		ii.dictionaries.Reset(field)
+	}
+
+	ii.index[field][lemma][page] = append(ii.index[field][lemma][page], position)
+}
+
+// SetDocLength запоминает кол-во токенов в поле страницы
+func (ii *InvertedIndex) SetDocLength(field string, page int, length int) {
+	if _, ok := ii.lengths[field]; !ok {
+		ii.lengths[field] = make(map[int]int)
+	}
+
+	ii.lengths[field][page] = length
 }
 
-func (ii *InvertedIndex) GetIndex() map[string][]int {
+// Dictionary возвращает отсортированный словарь лемм поля
+func (ii *InvertedIndex) Dictionary(field string) *TermDictionary {
+	return ii.dictionaries.Get(field, func() []string { return ii.Terms(field) })
+}
+
+// SetMetadata запоминает заголовок индекса
+func (ii *InvertedIndex) SetMetadata(metadata Metadata) {
+	ii.metadata = metadata
+}
+
+func (ii *InvertedIndex) Metadata() Metadata {
+	return ii.metadata
+}
+
+func (ii *InvertedIndex) GetIndex() map[string]map[string]map[int][]int {
 	return ii.index
 }
+
+func (ii *InvertedIndex) GetLengths() map[string]map[int]int {
+	return ii.lengths
+}
+
+// Pages возвращает отсортированный список страниц, в поле которых встречается лемма
+func (ii *InvertedIndex) Pages(field string, lemma string) []int {
+	pages := make([]int, 0, len(ii.index[field][lemma]))
+	for page := range ii.index[field][lemma] {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	return pages
+}
+
+// Positions возвращает позиции токенов леммы в поле страницы
+func (ii *InvertedIndex) Positions(field string, lemma string, page int) []int {
+	return ii.index[field][lemma][page]
+}
+
+// Frequency возвращает кол-во вхождений леммы в поле страницы
+func (ii *InvertedIndex) Frequency(field string, lemma string, page int) int {
+	return len(ii.index[field][lemma][page])
+}
+
+// DocLength возвращает кол-во токенов в поле страницы
+func (ii *InvertedIndex) DocLength(field string, page int) int {
+	return ii.lengths[field][page]
+}
+
+// Postings возвращает позиции леммы в поле по номерам страниц
+func (ii *InvertedIndex) Postings(field string, lemma string) map[int][]int {
+	return ii.index[field][lemma]
+}
+
+// Terms возвращает отсортированный список лемм поля
+func (ii *InvertedIndex) Terms(field string) []string {
+	terms := make([]string, 0, len(ii.index[field]))
+	for lemma := range ii.index[field] {
+		terms = append(terms, lemma)
+	}
+	sort.Strings(terms)
+
+	return terms
+}
+
+// AllPages возвращает отсортированный список всех страниц индекса
+func (ii *InvertedIndex) AllPages() []int {
+	set := make(map[int]bool)
+	for _, lemmas := range ii.index {
+		for _, postings := range lemmas {
+			for page := range postings {
+				set[page] = true
+			}
+		}
+	}
+	for _, lengths := range ii.lengths {
+		for page := range lengths {
+			set[page] = true
+		}
+	}
+
+	pages := make([]int, 0, len(set))
+	for page := range set {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	return pages
+}
+
+// FieldVectors строит TF-IDF векторы страниц по вхождениям лемм в поле.
+// TF считается относительно кол-ва токенов в поле страницы, IDF - по страницам, в поле которых есть лемма
+func FieldVectors(index Index, field string) *VectorIndex {
+	vectors := NewVectorIndex()
+	totalPages := float64(len(index.AllPages()))
+
+	for _, lemma := range index.Terms(field) {
+		postings := index.Postings(field, lemma)
+		idf := math.Log(totalPages / float64(len(postings)))
+		for page, positions := range postings {
+			length := index.DocLength(field, page)
+			if length == 0 {
+				continue
+			}
+
+			tf := float64(len(positions)) / float64(length)
+			vectors.Add(page, lemma, idf, tf*idf)
+		}
+	}
+
+	return vectors
+}
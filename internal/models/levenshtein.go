@@ -0,0 +1,128 @@
+package models
+
+import (
+	"oip-course/internal/search"
+	"sort"
+	"strings"
+)
+
+// FuzzyMatch термин словаря на расстоянии Левенштейна Distance от искомого слова
+type FuzzyMatch struct {
+	Term     string
+	Distance int
+}
+
+// AutoFuzzyDistance возвращает расстояние, с которым ищется слово в режиме исправления опечаток:
+// короткие слова ищутся точно, слова из 3-5 символов - с одной ошибкой, длинные - с двумя
+func AutoFuzzyDistance(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	default:
+		return search.MaxFuzzyDistance
+	}
+}
+
+// levenshteinAutomaton автомат, допускающий слова на расстоянии Левенштейна не больше max от word.
+// Состояние - строка таблицы динамического программирования: расстояния от прочитанного префикса до префиксов word
+type levenshteinAutomaton struct {
+	word []rune
+	max  int
+}
+
+// start возвращает состояние до чтения первого символа
+func (a levenshteinAutomaton) start() []int {
+	state := make([]int, len(a.word)+1)
+	for i := range state {
+		state[i] = i
+	}
+
+	return state
+}
+
+// step возвращает состояние после чтения символа r
+func (a levenshteinAutomaton) step(state []int, r rune) []int {
+	next := make([]int, len(state))
+	next[0] = state[0] + 1
+	for i, w := range a.word {
+		cost := 1
+		if w == r {
+			cost = 0
+		}
+		next[i+1] = min(state[i]+cost, state[i+1]+1, next[i]+1)
+	}
+
+	return next
+}
+
+// distance возвращает расстояние от прочитанного слова до word
+func (a levenshteinAutomaton) distance(state []int) int {
+	return state[len(state)-1]
+}
+
+// canMatch проверяет, что у прочитанного префикса есть продолжение, которое автомат допустит
+func (a levenshteinAutomaton) canMatch(state []int) bool {
+	for _, d := range state {
+		if d <= a.max {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fuzzy возвращает термины словаря на расстоянии Левенштейна не больше distance от word,
+// отсортированные по расстоянию, а при равном расстоянии - по алфавиту.
+// Словарь обходится как префиксное дерево: состояния автомата для общего с предыдущим термином префикса
+// переиспользуются, а если префикс не может привести к совпадению, все термины с этим префиксом пропускаются
+func (d *TermDictionary) Fuzzy(word string, distance int) []FuzzyMatch {
+	a := levenshteinAutomaton{word: []rune(word), max: distance}
+
+	var (
+		matches  []FuzzyMatch
+		previous []rune
+		states   = [][]int{a.start()} // states[k] - состояние после первых k символов текущего термина
+	)
+
+	for i := 0; i < len(d.terms); {
+		term := []rune(d.terms[i])
+
+		common := 0
+		for common < len(previous) && common < len(term) && common+1 < len(states) && previous[common] == term[common] {
+			common++
+		}
+		states = states[:common+1]
+
+		dead := -1
+		for k := common; k < len(term); k++ {
+			state := a.step(states[k], term[k])
+			states = append(states, state)
+			if !a.canMatch(state) {
+				dead = k + 1
+				break
+			}
+		}
+
+		if dead >= 0 {
+			// Ни один термин с этим префиксом не подходит, переходим к первому термину с другим префиксом
+			prefix := string(term[:dead])
+			i += sort.Search(len(d.terms)-i, func(j int) bool { return !strings.HasPrefix(d.terms[i+j], prefix) })
+			previous = term[:dead]
+			continue
+		}
+
+		if dist := a.distance(states[len(term)]); dist <= distance {
+			matches = append(matches, FuzzyMatch{Term: d.terms[i], Distance: dist})
+		}
+		previous = term
+		i++
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+
+	return matches
+}
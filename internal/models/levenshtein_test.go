@@ -0,0 +1,128 @@
+package models
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// levenshtein расстояние Левенштейна между строками по символам, полным перебором таблицы
+func levenshtein(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	row := make([]int, len(y)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			next := min(row[j]+1, row[j-1]+1, diagonal+cost)
+			diagonal, row[j] = row[j], next
+		}
+	}
+
+	return row[len(y)]
+}
+
+// fuzzyBruteForce сравнивает word с каждым термином словаря
+func fuzzyBruteForce(terms []string, word string, distance int) []FuzzyMatch {
+	var matches []FuzzyMatch
+	for _, term := range terms {
+		if dist := levenshtein(word, term); dist <= distance {
+			matches = append(matches, FuzzyMatch{Term: term, Distance: dist})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Term < matches[j].Term
+	})
+
+	return matches
+}
+
+// randomWord возвращает слово из букв alphabet длиной от 1 до maxLen
+func randomWord(rng *rand.Rand, alphabet []rune, maxLen int) string {
+	word := make([]rune, 1+rng.Intn(maxLen))
+	for i := range word {
+		word[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+
+	return string(word)
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "ген", 3},
+		{"ген", "ген", 0},
+		{"ген", "гены", 1},
+		{"гинетика", "генетика", 1},
+		{"эвалюция", "эволюция", 1},
+		{"клетка", "катлек", 4},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzy(t *testing.T) {
+	dictionary := NewTermDictionary([]string{"ген", "гена", "гены", "геном", "генетика", "генетик", "гений", "кен", "клетка", "эволюция"})
+
+	tests := []struct {
+		word     string
+		distance int
+		want     []FuzzyMatch
+	}{
+		{"ген", 0, []FuzzyMatch{{"ген", 0}}},
+		{"гин", 0, nil},
+		{"ген", 1, []FuzzyMatch{{"ген", 0}, {"гена", 1}, {"гены", 1}, {"кен", 1}}},
+		{"гинетика", 2, []FuzzyMatch{{"генетика", 1}, {"генетик", 2}}},
+		{"эвалюцея", 2, []FuzzyMatch{{"эволюция", 2}}},
+		{"", 3, []FuzzyMatch{{"ген", 3}, {"кен", 3}}},
+	}
+
+	for _, tt := range tests {
+		if got := dictionary.Fuzzy(tt.word, tt.distance); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Fuzzy(%q, %d) = %v, want %v", tt.word, tt.distance, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Маленький алфавит дает много терминов с общими префиксами и близких друг к другу
+	alphabet := []rune("агенкё")
+
+	terms := make([]string, 2_000)
+	for i := range terms {
+		terms[i] = randomWord(rng, alphabet, 8)
+	}
+	dictionary := NewTermDictionary(terms)
+
+	for range 300 {
+		word := randomWord(rng, alphabet, 9)
+		for distance := range 3 {
+			got := dictionary.Fuzzy(word, distance)
+			want := fuzzyBruteForce(dictionary.Terms(), word, distance)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Fuzzy(%q, %d) = %v, want %v", word, distance, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"oip-course/internal/analysis"
+	"time"
+)
+
+// MetadataVersion версия формата заголовка индекса
+const MetadataVersion = 1
+
+// Metadata заголовок индекса: как и из каких файлов индекс построен
+type Metadata struct {
+	Version  int               `json:"version"`   // Версия формата заголовка, 0 - индекс построен без заголовка
+	Analyzer analysis.Config   `json:"analyzer"`  // Конвейер анализа, которым строился индекс
+	DocCount int               `json:"doc_count"` // Кол-во страниц индекса
+	BuiltAt  time.Time         `json:"built_at"`  // Время построения
+	Sources  map[string]string `json:"sources"`   // Путь к исходному файлу -> SHA-256 его содержимого
+}
+
+// CheckAnalyzer проверяет, что заголовок поддерживается и запросы разбираются тем же конвейером анализа,
+// которым строился индекс
+func (m Metadata) CheckAnalyzer(config analysis.Config) error {
+	switch {
+	case m.Version == 0:
+		return fmt.Errorf("index has no metadata, the analyzer it was built with is unknown")
+	case m.Version != MetadataVersion:
+		return fmt.Errorf("unsupported index metadata version %d, expected %d", m.Version, MetadataVersion)
+	case m.Analyzer.String() != config.String():
+		return fmt.Errorf("index was built with analyzer %q, but queries are analyzed with %q", m.Analyzer, config)
+	}
+
+	return nil
+}
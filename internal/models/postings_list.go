@@ -0,0 +1,235 @@
+package models
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// postingsBlockSize кол-во номеров страниц в блоке сжатого списка
+const postingsBlockSize = 128
+
+// PostingsList сжатый отсортированный список номеров страниц без повторов.
+// Номера хранятся блоками по postingsBlockSize разницами между соседними номерами в VByte кодировке.
+// Для каждого блока хранится skip pointer - первый номер блока и смещение блока, по ним пересечение
+// перескакивает блоки, в которых заведомо нет нужных номеров. Нулевой указатель - пустой список
+type PostingsList struct {
+	data   []byte
+	skips  []skipPointer
+	length int
+	last   int // Последний номер списка, следующий добавляемый должен быть больше
+}
+
+// skipPointer начало блока сжатого списка
+type skipPointer struct {
+	first  int // Первый номер страницы блока
+	offset int // Смещение блока в data
+}
+
+// NewPostingsList сжимает отсортированный по возрастанию список номеров страниц без повторов
+func NewPostingsList(pages []int) *PostingsList {
+	pl := &PostingsList{}
+	for _, page := range pages {
+		pl.add(page)
+	}
+
+	return pl
+}
+
+// add дописывает в конец списка номер страницы, больший всех номеров списка
+func (pl *PostingsList) add(page int) {
+	if pl.length%postingsBlockSize == 0 {
+		// Первый номер блока записываем целиком, чтобы блок можно было разобрать независимо от предыдущих
+		pl.skips = append(pl.skips, skipPointer{first: page, offset: len(pl.data)})
+		pl.data = binary.AppendUvarint(pl.data, uint64(page))
+	} else {
+		pl.data = binary.AppendUvarint(pl.data, uint64(page-pl.last))
+	}
+
+	pl.last = page
+	pl.length++
+}
+
+// Len возвращает кол-во страниц в списке
+func (pl *PostingsList) Len() int {
+	if pl == nil {
+		return 0
+	}
+	return pl.length
+}
+
+// Size возвращает размер сжатых данных списка в байтах
+func (pl *PostingsList) Size() int {
+	if pl == nil {
+		return 0
+	}
+	return len(pl.data)
+}
+
+// Pages возвращает разжатый список номеров страниц
+func (pl *PostingsList) Pages() []int {
+	pages := make([]int, 0, pl.Len())
+	for it := pl.Iterator(); it.Next(); {
+		pages = append(pages, it.Page())
+	}
+
+	return pages
+}
+
+// Iterator возвращает итератор по списку, установленный перед первым номером
+func (pl *PostingsList) Iterator() *PostingsIterator {
+	if pl == nil {
+		pl = &PostingsList{}
+	}
+
+	return &PostingsIterator{list: pl, block: -1}
+}
+
+// PostingsIterator последовательно разжимает сжатый список по блокам
+type PostingsIterator struct {
+	list   *PostingsList
+	block  int   // Номер текущего блока
+	pages  []int // Разжатые номера текущего блока
+	cursor int   // Позиция текущего номера в блоке
+}
+
+// Next переходит к следующему номеру, возвращает false, если список закончился
+func (it *PostingsIterator) Next() bool {
+	if it.block >= 0 && it.cursor+1 < len(it.pages) {
+		it.cursor++
+		return true
+	}
+
+	return it.load(it.block + 1)
+}
+
+// Page возвращает текущий номер страницы
+func (it *PostingsIterator) Page() int {
+	return it.pages[it.cursor]
+}
+
+// Advance переходит к первому номеру, не меньшему target, и возвращает false, если такого номера нет.
+// Блоки, последний номер которых меньше target, пропускаются без разжатия
+func (it *PostingsIterator) Advance(target int) bool {
+	if it.block < 0 && !it.Next() {
+		return false
+	}
+	if it.block >= len(it.list.skips) {
+		return false
+	}
+
+	// Если target дальше текущего блока, ищем по skip pointer последний блок, который начинается не позже target
+	if target > it.pages[len(it.pages)-1] {
+		skips := it.list.skips
+		next := it.block + 1 + sort.Search(len(skips)-it.block-1, func(i int) bool {
+			return skips[it.block+1+i].first > target
+		})
+		if !it.load(max(next-1, it.block+1)) {
+			return false
+		}
+	}
+
+	for it.pages[it.cursor] < target {
+		if !it.Next() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// load разжимает блок с номером block и устанавливает итератор на его первый номер
+func (it *PostingsIterator) load(block int) bool {
+	it.block = block
+	if block >= len(it.list.skips) {
+		it.pages = it.pages[:0]
+		return false
+	}
+
+	end := len(it.list.data)
+	if block+1 < len(it.list.skips) {
+		end = it.list.skips[block+1].offset
+	}
+	data := it.list.data[it.list.skips[block].offset:end]
+
+	it.pages = it.pages[:0]
+	page := 0
+	for len(data) > 0 {
+		delta, n := binary.Uvarint(data)
+		data = data[n:]
+		page += int(delta)
+		it.pages = append(it.pages, page)
+	}
+	it.cursor = 0
+
+	return true
+}
+
+// Intersect возвращает пересечение сжатых списков. Короткий список обходится целиком,
+// в длинном итератор перескакивает к нужному номеру по skip pointer
+func Intersect(a, b *PostingsList) *PostingsList {
+	if a.Len() > b.Len() {
+		a, b = b, a
+	}
+
+	result := &PostingsList{}
+	long := b.Iterator()
+	for short := a.Iterator(); short.Next(); {
+		if !long.Advance(short.Page()) {
+			break
+		}
+		if long.Page() == short.Page() {
+			result.add(short.Page())
+		}
+	}
+
+	return result
+}
+
+// Union возвращает объединение сжатых списков
+func Union(a, b *PostingsList) *PostingsList {
+	result := &PostingsList{}
+	left, right := a.Iterator(), b.Iterator()
+	hasLeft, hasRight := left.Next(), right.Next()
+
+	for hasLeft && hasRight {
+		switch {
+		case left.Page() == right.Page():
+			result.add(left.Page())
+			hasLeft, hasRight = left.Next(), right.Next()
+		case left.Page() < right.Page():
+			result.add(left.Page())
+			hasLeft = left.Next()
+		default:
+			result.add(right.Page())
+			hasRight = right.Next()
+		}
+	}
+
+	for ; hasLeft; hasLeft = left.Next() {
+		result.add(left.Page())
+	}
+	for ; hasRight; hasRight = right.Next() {
+		result.add(right.Page())
+	}
+
+	return result
+}
+
+// Difference возвращает номера списка a, которых нет в списке b
+func Difference(a, b *PostingsList) *PostingsList {
+	result := &PostingsList{}
+	excluded := b.Iterator()
+	hasExcluded := true
+
+	for it := a.Iterator(); it.Next(); {
+		if hasExcluded {
+			hasExcluded = excluded.Advance(it.Page())
+		}
+		if hasExcluded && excluded.Page() == it.Page() {
+			continue
+		}
+		result.add(it.Page())
+	}
+
+	return result
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// benchmarkUniverse наибольший номер страницы в сгенерированных списках
+const benchmarkUniverse = 1_000_000
+
+// benchmarkSizes пары размеров списков: одинаковые, а также короткий с длинным, где skip pointer дают наибольший выигрыш
+var benchmarkSizes = [][2]int{{1_000, 1_000}, {100_000, 100_000}, {100, 100_000}, {1_000, 500_000}}
+
+// randomPages возвращает отсортированный список из n различных номеров страниц от 1 до universe
+func randomPages(rng *rand.Rand, n int, universe int) []int {
+	set := make(map[int]bool, n)
+	for len(set) < n {
+		set[rng.Intn(universe)+1] = true
+	}
+
+	pages := make([]int, 0, n)
+	for page := range set {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	return pages
+}
+
+// intersectSlices пересечение отсортированных срезов слиянием, как в поиске до сжатых списков
+func intersectSlices(a, b []int) []int {
+	var result []int
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			result = append(result, a[i])
+			i++
+			j++
+		} else if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return result
+}
+
+// unionSlices объединение отсортированных срезов слиянием, как в поиске до сжатых списков
+func unionSlices(a, b []int) []int {
+	var result []int
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			result = append(result, a[i])
+			i++
+			j++
+		} else if a[i] < b[j] {
+			result = append(result, a[i])
+			i++
+		} else {
+			result = append(result, b[j])
+			j++
+		}
+	}
+
+	result = append(result, a[i:]...)
+	result = append(result, b[j:]...)
+
+	return result
+}
+
+// differenceSlices элементы отсортированного среза a, которых нет в отсортированном срезе b
+func differenceSlices(a, b []int) []int {
+	var result []int
+	j := 0
+
+	for _, x := range a {
+		for j < len(b) && b[j] < x {
+			j++
+		}
+		if j < len(b) && b[j] == x {
+			continue
+		}
+		result = append(result, x)
+	}
+
+	return result
+}
+
+// pageRange возвращает номера страниц from, from+step, ... меньше to
+func pageRange(from, to, step int) []int {
+	var pages []int
+	for page := from; page < to; page += step {
+		pages = append(pages, page)
+	}
+
+	return pages
+}
+
+func TestPostingsListPages(t *testing.T) {
+	for _, pages := range [][]int{nil, {7}, pageRange(1, postingsBlockSize+1, 1), pageRange(3, 10_000, 7)} {
+		list := NewPostingsList(pages)
+		if list.Len() != len(pages) {
+			t.Errorf("Len() = %d, want %d", list.Len(), len(pages))
+		}
+		if got := list.Pages(); !slices.Equal(got, pages) && len(got)+len(pages) > 0 {
+			t.Errorf("Pages() of %d pages differs from the input", len(pages))
+		}
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name string
+		a, b []int
+	}{
+		{"empty", nil, pageRange(1, 1000, 3)},
+		{"disjoint", pageRange(1, 1000, 2), pageRange(2, 1000, 2)},
+		{"equal", pageRange(1, 1000, 3), pageRange(1, 1000, 3)},
+		{"block boundary", pageRange(1, 3*postingsBlockSize, 1), []int{postingsBlockSize - 1, postingsBlockSize, postingsBlockSize + 1, 2 * postingsBlockSize}},
+		{"short and long", randomPages(rng, 100, 100_000), randomPages(rng, 20_000, 100_000)},
+		{"random", randomPages(rng, 5_000, 20_000), randomPages(rng, 5_000, 20_000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NewPostingsList(tt.a), NewPostingsList(tt.b)
+
+			for _, op := range []struct {
+				name string
+				got  *PostingsList
+				want []int
+			}{
+				{"Intersect", Intersect(a, b), intersectSlices(tt.a, tt.b)},
+				{"Intersect reversed", Intersect(b, a), intersectSlices(tt.a, tt.b)},
+				{"Union", Union(a, b), unionSlices(tt.a, tt.b)},
+				{"Difference", Difference(a, b), differenceSlices(tt.a, tt.b)},
+				{"Difference reversed", Difference(b, a), differenceSlices(tt.b, tt.a)},
+			} {
+				if got := op.got.Pages(); !slices.Equal(got, op.want) && len(got)+len(op.want) > 0 {
+					t.Errorf("%s: got %d pages, want %d", op.name, len(got), len(op.want))
+				}
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	// Номера кратны 3, блоки по postingsBlockSize номеров
+	pages := pageRange(3, 3*5*postingsBlockSize, 3)
+	last := pages[len(pages)-1]
+
+	tests := []struct {
+		targets []int // Последовательные вызовы Advance
+		want    []int // Ожидаемые номера, 0 - Advance возвращает false
+	}{
+		{[]int{1}, []int{3}},
+		{[]int{3}, []int{3}},
+		{[]int{4}, []int{6}},
+		{[]int{3 * postingsBlockSize}, []int{3 * postingsBlockSize}},                             // Последний номер первого блока
+		{[]int{3*postingsBlockSize + 1}, []int{3*postingsBlockSize + 3}},                         // Первый номер второго блока
+		{[]int{10, 10, 11}, []int{12, 12, 12}},                                                   // Повтор не сдвигает итератор
+		{[]int{10, 3*3*postingsBlockSize + 2, last}, []int{12, 3*3*postingsBlockSize + 3, last}}, // Пропуск блоков
+		{[]int{last + 1}, []int{0}},
+		{[]int{last, last + 1}, []int{last, 0}},
+	}
+
+	for _, tt := range tests {
+		it := NewPostingsList(pages).Iterator()
+		for i, target := range tt.targets {
+			ok := it.Advance(target)
+			switch {
+			case tt.want[i] == 0 && ok:
+				t.Errorf("Advance(%v): step %d returned %d, want false", tt.targets, i, it.Page())
+			case tt.want[i] != 0 && !ok:
+				t.Errorf("Advance(%v): step %d returned false, want %d", tt.targets, i, tt.want[i])
+			case ok && it.Page() != tt.want[i]:
+				t.Errorf("Advance(%v): step %d = %d, want %d", tt.targets, i, it.Page(), tt.want[i])
+			}
+		}
+	}
+}
+
+func TestAdvanceAfterNext(t *testing.T) {
+	pages := pageRange(1, 4*postingsBlockSize, 2)
+	it := NewPostingsList(pages).Iterator()
+
+	var got []int
+	for it.Next() {
+		got = append(got, it.Page())
+		// Каждые 50 номеров перескакиваем вперед через границу блока
+		if len(got)%50 == 0 && it.Advance(it.Page()+2*postingsBlockSize) {
+			got = append(got, it.Page())
+		}
+	}
+
+	var want []int
+	for i := 0; i < len(pages); i++ {
+		want = append(want, pages[i])
+		if len(want)%50 == 0 {
+			target := pages[i] + 2*postingsBlockSize
+			j := sort.SearchInts(pages, target)
+			if j == len(pages) {
+				break
+			}
+			want = append(want, pages[j])
+			i = j
+		}
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("Next and Advance visited %v, want %v", got, want)
+	}
+}
+
+// benchmarkPair генерирует пару списков заданных размеров
+func benchmarkPair(size [2]int) (a, b []int) {
+	rng := rand.New(rand.NewSource(1))
+	return randomPages(rng, size[0], benchmarkUniverse), randomPages(rng, size[1], benchmarkUniverse)
+}
+
+func BenchmarkIntersectSlices(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		x, y := benchmarkPair(size)
+		b.Run(fmt.Sprintf("%d/%d", size[0], size[1]), func(b *testing.B) {
+			for range b.N {
+				intersectSlices(x, y)
+			}
+		})
+	}
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		x, y := benchmarkPair(size)
+		listX, listY := NewPostingsList(x), NewPostingsList(y)
+		b.Run(fmt.Sprintf("%d/%d", size[0], size[1]), func(b *testing.B) {
+			b.ReportMetric(float64(listX.Size()+listY.Size()), "list-bytes")
+			b.ReportMetric(float64(8*(len(x)+len(y))), "slice-bytes")
+			for range b.N {
+				Intersect(listX, listY)
+			}
+		})
+	}
+}
+
+func BenchmarkUnionSlices(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		x, y := benchmarkPair(size)
+		b.Run(fmt.Sprintf("%d/%d", size[0], size[1]), func(b *testing.B) {
+			for range b.N {
+				unionSlices(x, y)
+			}
+		})
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		x, y := benchmarkPair(size)
+		listX, listY := NewPostingsList(x), NewPostingsList(y)
+		b.Run(fmt.Sprintf("%d/%d", size[0], size[1]), func(b *testing.B) {
+			for range b.N {
+				Union(listX, listY)
+			}
+		})
+	}
+}
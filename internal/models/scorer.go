@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// Названия моделей ранжирования
+const (
+	ScorerTFIDF = "tfidf" // Косинусное сходство TF-IDF векторов
+	ScorerBM25  = "bm25"  // Okapi BM25
+)
+
+// Scorers список доступных моделей ранжирования
+var Scorers = []string{ScorerTFIDF, ScorerBM25}
+
+// Scorer модель ранжирования страниц по одному полю документа
+type Scorer interface {
+	// Search возвращает top-k страниц (все при k <= 0), отсортированных по убыванию оценки
+	Search(lemmas []string, k int) []SearchResult
+}
+
+// BM25Params параметры BM25: k1 - насыщение частоты леммы, b - степень нормализации по длине поля
+type BM25Params struct {
+	K1 float64
+	B  float64
+}
+
+// DefaultBM25Params параметры BM25 по умолчанию
+var DefaultBM25Params = BM25Params{K1: 1.2, B: 0.75}
+
+// Validate проверяет допустимость параметров
+func (p BM25Params) Validate() error {
+	if p.K1 < 0 || p.B < 0 || p.B > 1 {
+		return fmt.Errorf("invalid BM25 parameters: k1 must be non-negative and b must be in [0, 1], got k1=%g b=%g", p.K1, p.B)
+	}
+	return nil
+}
+
+// BM25 ранжирует страницы по полю индекса моделью Okapi BM25
+type BM25 struct {
+	index     Index
+	field     string
+	params    BM25Params
+	pages     int     // Кол-во страниц индекса
+	avgLength float64 // Средняя длина поля в токенах
+}
+
+func NewBM25(index Index, field string, params BM25Params) *BM25 {
+	pages := index.AllPages()
+
+	var total int
+	for _, page := range pages {
+		total += index.DocLength(field, page)
+	}
+
+	var avgLength float64
+	if len(pages) > 0 {
+		avgLength = float64(total) / float64(len(pages))
+	}
+
+	return &BM25{
+		index:     index,
+		field:     field,
+		params:    params,
+		pages:     len(pages),
+		avgLength: avgLength,
+	}
+}
+
+// Search возвращает top-k страниц, отсортированных по сумме BM25 оценок лемм запроса
+func (bm *BM25) Search(lemmas []string, k int) []SearchResult {
+	if bm.avgLength == 0 {
+		return nil
+	}
+
+	scores := make(map[int]float64)
+	seen := make(map[string]bool)
+	for _, lemma := range lemmas {
+		if seen[lemma] {
+			continue
+		}
+		seen[lemma] = true
+
+		postings := bm.index.Postings(bm.field, lemma)
+		if len(postings) == 0 {
+			continue
+		}
+
+		// IDF в варианте с +1 под логарифмом не бывает отрицательным для частых лемм
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(bm.pages)-df+0.5)/(df+0.5))
+
+		for page, positions := range postings {
+			tf := float64(len(positions))
+			norm := 1 - bm.params.B + bm.params.B*float64(bm.index.DocLength(bm.field, page))/bm.avgLength
+			scores[page] += idf * tf * (bm.params.K1 + 1) / (tf + bm.params.K1*norm)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for page, score := range scores {
+		results = append(results, SearchResult{Page: page, Score: score})
+	}
+
+	return topResults(results, k)
+}
+
+// BM25Scorers возвращает BM25 для каждого поля документа
+func BM25Scorers(index Index, params BM25Params) map[string]Scorer {
+	scorers := make(map[string]Scorer, len(Fields))
+	for _, field := range Fields {
+		scorers[field] = NewBM25(index, field, params)
+	}
+
+	return scorers
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultBoosts веса полей документа в ранжированном поиске по умолчанию
+var DefaultBoosts = map[string]float64{
+	FieldTitle: 3,
+	FieldTags:  2,
+	FieldLead:  1.5,
+	FieldBody:  1,
+}
+
+// SearchResult результат ранжированного поиска: номер страницы и ее оценка
+type SearchResult struct {
+	Page  int
+	Score float64
+}
+
+// VectorIndex хранит TF-IDF векторы страниц для поиска в векторной модели
+type VectorIndex struct {
+	vectors map[int]map[string]float64 // номер страницы -> лемма -> TF-IDF
+	norms   map[int]float64            // номер страницы -> квадрат длины вектора
+	idf     map[string]float64         // лемма -> IDF
+}
+
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{
+		vectors: make(map[int]map[string]float64),
+		norms:   make(map[int]float64),
+		idf:     make(map[string]float64),
+	}
+}
+
+// Add добавляет в вектор страницы вес леммы и запоминает IDF леммы
+func (vi *VectorIndex) Add(page int, lemma string, idf, tfIdf float64) {
+	if _, ok := vi.vectors[page]; !ok {
+		vi.vectors[page] = make(map[string]float64)
+	}
+
+	vi.norms[page] += tfIdf*tfIdf - vi.vectors[page][lemma]*vi.vectors[page][lemma]
+	vi.vectors[page][lemma] = tfIdf
+	vi.idf[lemma] = idf
+}
+
+// Search возвращает top-k страниц, отсортированных по косинусному сходству с запросом
+func (vi *VectorIndex) Search(lemmas []string, k int) []SearchResult {
+	if len(lemmas) == 0 {
+		return nil
+	}
+
+	// Строим TF-IDF вектор запроса
+	query := make(map[string]float64)
+	for _, lemma := range lemmas {
+		query[lemma]++
+	}
+
+	var queryNorm float64
+	for lemma, count := range query {
+		query[lemma] = count / float64(len(lemmas)) * vi.idf[lemma]
+		queryNorm += query[lemma] * query[lemma]
+	}
+	if queryNorm == 0 {
+		return nil
+	}
+
+	results := make([]SearchResult, 0)
+	for page, vector := range vi.vectors {
+		var dot float64
+		for lemma, weight := range query {
+			dot += weight * vector[lemma]
+		}
+		if dot == 0 || vi.norms[page] == 0 {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Page:  page,
+			Score: dot / (math.Sqrt(queryNorm) * math.Sqrt(vi.norms[page])),
+		})
+	}
+
+	return topResults(results, k)
+}
+
+// SearchFields возвращает top-k страниц, отсортированных по сумме оценок полей страницы, умноженных на вес поля
+func SearchFields(fields map[string]Scorer, boosts map[string]float64, lemmas []string, k int) []SearchResult {
+	scores := make(map[int]float64)
+	for field, scorer := range fields {
+		if boosts[field] <= 0 {
+			continue
+		}
+
+		for _, result := range scorer.Search(lemmas, 0) {
+			scores[result.Page] += boosts[field] * result.Score
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for page, score := range scores {
+		results = append(results, SearchResult{Page: page, Score: score})
+	}
+
+	return topResults(results, k)
+}
+
+// ParseBoosts разбирает веса полей в формате "title=3,tags=2". Не указанные поля получают вес по умолчанию
+func ParseBoosts(s string) (map[string]float64, error) {
+	boosts := make(map[string]float64, len(DefaultBoosts))
+	for field, boost := range DefaultBoosts {
+		boosts[field] = boost
+	}
+
+	if strings.TrimSpace(s) == "" {
+		return boosts, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		field, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || !slices.Contains(Fields, field) {
+			return nil, fmt.Errorf("invalid field boost %q", item)
+		}
+
+		boost, err := strconv.ParseFloat(value, 64)
+		if err != nil || boost < 0 {
+			return nil, fmt.Errorf("invalid field boost %q", item)
+		}
+		boosts[field] = boost
+	}
+
+	return boosts, nil
+}
+
+// topResults сортирует результаты по убыванию оценки и оставляет первые k (все при k <= 0)
+func topResults(results []SearchResult, k int) []SearchResult {
+	// При равных оценках сначала идут страницы с меньшим номером
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Page < results[j].Page
+	})
+
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+
+	return results
+}
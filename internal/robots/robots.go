@@ -0,0 +1,233 @@
+package robots
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule правило Allow/Disallow для шаблона пути
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// group группа правил для набора User-Agent
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// Rules правила robots.txt, выбранные для одного User-Agent
+type Rules struct {
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// AllowAll правила, разрешающие обход всего сайта
+func AllowAll() *Rules {
+	return &Rules{}
+}
+
+// DisallowAll правила, запрещающие обход всего сайта
+func DisallowAll() *Rules {
+	return &Rules{rules: []rule{{pattern: "/", allow: false}}}
+}
+
+// Parse разбирает robots.txt и выбирает правила для userAgent. Если для него нет отдельной группы,
+// используется группа "*". Группы с одинаковым User-Agent объединяются
+func Parse(r io.Reader, userAgent string) (*Rules, error) {
+	var (
+		groups  []*group
+		current *group
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Пустое название не относится ни к одному роботу
+			if value == "" {
+				continue
+			}
+
+			// Подряд идущие строки User-Agent относятся к одной группе
+			if current == nil || len(current.rules) > 0 || current.crawlDelay > 0 {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			// Пустой Disallow ничего не запрещает
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, rule{pattern: value, allow: key == "allow"})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return selectRules(groups, userAgent), nil
+}
+
+// selectRules объединяет группы, подходящие под userAgent, или группы "*", если подходящих нет
+func selectRules(groups []*group, userAgent string) *Rules {
+	agent := strings.ToLower(productToken(userAgent))
+
+	specific, wildcard := &Rules{}, &Rules{}
+	foundSpecific := false
+
+	for _, g := range groups {
+		switch {
+		case g.matches(agent):
+			foundSpecific = true
+			specific.rules = append(specific.rules, g.rules...)
+			specific.crawlDelay = max(specific.crawlDelay, g.crawlDelay)
+		case g.matches("*"):
+			wildcard.rules = append(wildcard.rules, g.rules...)
+			wildcard.crawlDelay = max(wildcard.crawlDelay, g.crawlDelay)
+		}
+	}
+
+	if foundSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// matches проверяет, относится ли группа к роботу agent. Названия сравниваются целиком без учета регистра
+// (RFC 9309): группа "crawler" не относится к роботу "oip-crawler". Для "*" ищется группа "*"
+func (g *group) matches(agent string) bool {
+	for _, a := range g.agents {
+		if a == agent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// productToken возвращает название робота из строки User-Agent, например "oip-crawler" из "oip-crawler/1.0"
+func productToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
+
+// Allowed проверяет, разрешен ли обход пути (с query-строкой). Побеждает правило с самым длинным
+// шаблоном, при равной длине - Allow
+func (r *Rules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	allowed, matchedLength := true, -1
+	for _, rl := range r.rules {
+		if !match(rl.pattern, path) {
+			continue
+		}
+
+		if len(rl.pattern) > matchedLength || (len(rl.pattern) == matchedLength && rl.allow) {
+			allowed, matchedLength = rl.allow, len(rl.pattern)
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay возвращает паузу между запросами, указанную в Crawl-delay, или 0
+func (r *Rules) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// match сопоставляет путь с шаблоном robots.txt: "*" - любая последовательность символов,
+// "$" в конце - конец пути, иначе шаблон сопоставляется с началом пути
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// Первая часть должна совпадать с началом пути
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		// Последнюю часть якорного шаблона ищем в конце пути
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
+
+// Fetch загружает и разбирает robots.txt сайта, на котором находится pageURL.
+// По RFC 9309 при ответе 4xx обход разрешен полностью, при ответе 5xx или ошибке сети - запрещен
+func Fetch(ctx context.Context, client *http.Client, pageURL, userAgent string) (*Rules, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	robotsURL := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return DisallowAll(), fmt.Errorf("get %s: %w", robotsURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Parse(resp.Body, userAgent)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return AllowAll(), nil
+	default:
+		return DisallowAll(), fmt.Errorf("get %s: server returned error status code: %v", robotsURL, resp.StatusCode)
+	}
+}
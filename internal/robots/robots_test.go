@@ -0,0 +1,189 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const userAgent = "oip-crawler/1.0"
+
+func parse(t *testing.T, robotsTxt string) *Rules {
+	t.Helper()
+
+	rules, err := Parse(strings.NewReader(robotsTxt), userAgent)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	return rules
+}
+
+func TestAllowed(t *testing.T) {
+	rules := parse(t, `
+User-agent: *
+Disallow: /news/
+Allow: /news/public/
+Disallow: /*.pdf$
+Disallow: /search*sort=
+Allow: /page$
+Disallow: /page
+`)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"", true},
+		{"/news/", false},
+		{"/news/1", false},
+		{"/news/public/1", true}, // Побеждает более длинное правило
+		{"/files/a.pdf", false},
+		{"/files/a.pdf?x=1", true}, // "$" - конец пути
+		{"/search?q=ген&sort=date", false},
+		{"/search?q=ген", true},
+		{"/page", true},
+		{"/page/2", false},
+	}
+
+	for _, tt := range tests {
+		if got := rules.Allowed(tt.path); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedEqualLength(t *testing.T) {
+	rules := parse(t, `
+User-agent: *
+Disallow: /a
+Allow: /a
+`)
+
+	if !rules.Allowed("/a") {
+		t.Error("Allowed(/a) = false, want Allow to win a tie")
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	rules := parse(t, `
+User-agent: *
+Crawl-delay: 1.5
+Disallow: /private
+`)
+
+	if got, want := rules.CrawlDelay(), 1500*time.Millisecond; got != want {
+		t.Errorf("CrawlDelay() = %v, want %v", got, want)
+	}
+}
+
+func TestSpecificGroup(t *testing.T) {
+	rules := parse(t, `
+User-agent: *
+Disallow: /
+
+User-agent: other-bot
+Disallow: /other
+
+User-agent: OIP-Crawler
+Disallow: /private
+Crawl-delay: 2
+`)
+
+	if !rules.Allowed("/news/1") {
+		t.Error("Allowed(/news/1) = false, want the agent group to override *")
+	}
+	if rules.Allowed("/private/1") {
+		t.Error("Allowed(/private/1) = true, want false")
+	}
+	if !rules.Allowed("/other") {
+		t.Error("Allowed(/other) = false, want rules of other agents ignored")
+	}
+	if got, want := rules.CrawlDelay(), 2*time.Second; got != want {
+		t.Errorf("CrawlDelay() = %v, want %v", got, want)
+	}
+}
+
+func TestSubstringUserAgent(t *testing.T) {
+	rules := parse(t, `
+User-agent: *
+Disallow: /private
+
+User-agent: crawler
+User-agent: oip
+Disallow: /
+Crawl-delay: 10
+`)
+
+	if !rules.Allowed("/news/1") {
+		t.Error("Allowed(/news/1) = false, want groups named by a part of the agent ignored")
+	}
+	if rules.Allowed("/private/1") {
+		t.Error("Allowed(/private/1) = true, want the * group to apply")
+	}
+	if got := rules.CrawlDelay(); got != 0 {
+		t.Errorf("CrawlDelay() = %v, want 0", got)
+	}
+}
+
+func TestEmptyUserAgent(t *testing.T) {
+	rules := parse(t, `
+User-agent:
+Allow: /
+
+User-agent: *
+Disallow: /
+`)
+
+	if rules.Allowed("/news/1") {
+		t.Error("Allowed(/news/1) = true, want an empty User-agent to match no crawler")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantErr    bool
+		wantPublic bool // Разрешен ли /public
+		wantAdmin  bool // Разрешен ли /admin
+	}{
+		{name: "ok", status: http.StatusOK, body: "User-agent: *\nDisallow: /admin\n", wantPublic: true},
+		{name: "not found", status: http.StatusNotFound, wantPublic: true, wantAdmin: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/robots.txt" {
+					t.Errorf("requested %s, want /robots.txt", r.URL.Path)
+				}
+				if got := r.Header.Get("User-Agent"); got != userAgent {
+					t.Errorf("User-Agent = %q, want %q", got, userAgent)
+				}
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			rules, err := Fetch(context.Background(), server.Client(), server.URL+"/news/1", userAgent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch error = %v, want error: %v", err, tt.wantErr)
+			}
+
+			if got := rules.Allowed("/public"); got != tt.wantPublic {
+				t.Errorf("Allowed(/public) = %v, want %v", got, tt.wantPublic)
+			}
+			if got := rules.Allowed("/admin"); got != tt.wantAdmin {
+				t.Errorf("Allowed(/admin) = %v, want %v", got, tt.wantAdmin)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query узел синтаксического дерева булевого запроса
+type Query interface {
+	String() string
+	query()
+}
+
+// Term лемма слова запроса
+type Term struct {
+	Field string // Поле документа, в котором ищется слово. Пустое - текст статьи
+	Lemma string
+	Pos   int // Позиция слова в запросе (в символах)
+}
+
+// Phrase фраза в кавычках: леммы должны идти в поле страницы подряд
+type Phrase struct {
+	Field  string
+	Lemmas []string
+	Pos    int
+}
+
+// Wildcard шаблон слова: "*" заменяет любое кол-во символов ("генет*", "*фаг", "ген*аза").
+// Шаблон не лемматизируется и сравнивается с леммами индекса
+type Wildcard struct {
+	Field   string
+	Pattern string
+	Pos     int
+}
+
+// Fuzzy нечеткий поиск слова: подходят леммы индекса на расстоянии Левенштейна не больше Distance от леммы слова
+type Fuzzy struct {
+	Field    string
+	Lemma    string
+	Distance int
+	Pos      int
+}
+
+// Near оператор близости: вхождения операндов находятся не дальше Distance слов друг от друга
+type Near struct {
+	Left, Right Query
+	Distance    int
+}
+
+// And пересечение результатов операндов
+type And struct {
+	Left, Right Query
+}
+
+// Or объединение результатов операндов
+type Or struct {
+	Left, Right Query
+}
+
+// Not дополнение результата операнда до множества всех страниц
+type Not struct {
+	Operand Query
+}
+
+func (Term) query()     {}
+func (Phrase) query()   {}
+func (Wildcard) query() {}
+func (Fuzzy) query()    {}
+func (Near) query()     {}
+func (And) query()      {}
+func (Or) query()       {}
+func (Not) query()      {}
+
+func (t Term) String() string {
+	return fieldPrefix(t.Field) + t.Lemma
+}
+
+func (p Phrase) String() string {
+	return fieldPrefix(p.Field) + `"` + strings.Join(p.Lemmas, " ") + `"`
+}
+
+func (w Wildcard) String() string {
+	return fieldPrefix(w.Field) + w.Pattern
+}
+
+func (f Fuzzy) String() string {
+	return fmt.Sprintf("%s%s~%d", fieldPrefix(f.Field), f.Lemma, f.Distance)
+}
+
+func (n Near) String() string {
+	return fmt.Sprintf("(%s NEAR/%d %s)", n.Left, n.Distance, n.Right)
+}
+
+func (a And) String() string {
+	return fmt.Sprintf("(%s AND %s)", a.Left, a.Right)
+}
+
+func (o Or) String() string {
+	return fmt.Sprintf("(%s OR %s)", o.Left, o.Right)
+}
+
+func (n Not) String() string {
+	return fmt.Sprintf("NOT %s", n.Operand)
+}
+
+// DefaultField поле документа, в котором ищутся слова запроса без префикса поля, - текст статьи
+const DefaultField = "body"
+
+// QueryField возвращает поле документа, в котором ищется слово запроса с полем field. Пустое поле - DefaultField.
+// Функция объявлена здесь, а не в models, потому что models сам зависит от search
+func QueryField(field string) string {
+	if field == "" {
+		return DefaultField
+	}
+	return field
+}
+
+// fieldPrefix возвращает префикс поля для строкового представления запроса
+func fieldPrefix(field string) string {
+	if field == "" {
+		return ""
+	}
+	return field + ":"
+}
@@ -0,0 +1,433 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"oip-course/internal/analysis"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrWrongQuery ошибка синтаксиса булевого запроса
+var ErrWrongQuery = errors.New("wrong query")
+
+// MaxFuzzyDistance наибольшее расстояние Левенштейна нечеткого поиска, оно же расстояние по умолчанию.
+// При больших расстояниях под слово подходит слишком большая часть словаря
+const MaxFuzzyDistance = 2
+
+// ParseError ошибка разбора запроса с позицией (в символах), на которой она обнаружена
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v at position %d: %s", ErrWrongQuery, e.Pos, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrWrongQuery
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenNear
+	tokenLParen
+	tokenRParen
+)
+
+// fields префиксы полей в запросе и соответствующие им поля документа
+var fields = map[string]string{
+	"title": "title",
+	"lead":  "lead",
+	"body":  DefaultField,
+	"tag":   "tags",
+	"tags":  "tags",
+}
+
+// token лексема запроса
+type token struct {
+	kind     tokenKind
+	text     string
+	field    string // Поле, указанное префиксом слова или фразы
+	pos      int
+	end      int // Позиция после слова или фразы
+	distance int // Расстояние для оператора NEAR/n
+}
+
+// describe возвращает описание лексемы для сообщений об ошибках
+func (t token) describe() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return strconv.Quote(t.text)
+}
+
+// Parse разбирает булевый запрос и строит его синтаксическое дерево. Слова запроса приводятся к леммам
+// тем же анализатором, которым строился индекс.
+//
+// Грамматика в порядке возрастания приоритета:
+//
+//	or   = and { "OR" and }
+//	and  = not { "AND" not }
+//	not  = "NOT" not | near
+//	near = atom { "NEAR/n" atom }
+//	atom = [ field ":" ] ( word [ "~" [ n ] ] | pattern | '"' word { word } '"' ) | "(" or ")"
+//
+// Поле - title, lead, body или tag. Слова и фразы без префикса ищутся в тексте статьи.
+// Шаблон - слово со "*", заменяющей любое кол-во символов ("генет*", "*фаг", "ген*аза"), он сравнивается с леммами индекса.
+// Слово с "~n" ищется нечетко: подходят леммы на расстоянии Левенштейна не больше n (по умолчанию 2) от леммы слова.
+// Слова приводятся к терминам индекса анализатором, которым строился индекс
+func Parse(query string, analyzer *analysis.Analyzer) (Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, analyzer: analyzer}
+	if p.peek().kind == tokenEOF {
+		return nil, &ParseError{Pos: 0, Msg: "empty query"}
+	}
+
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		if t.kind == tokenRParen {
+			return nil, &ParseError{Pos: t.pos, Msg: "unexpected closing parenthesis"}
+		}
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected operator before %s", t.describe())}
+	}
+
+	return q, nil
+}
+
+// lex разбивает запрос на лексемы
+func lex(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		char := runes[i]
+
+		switch {
+		case unicode.IsSpace(char):
+			i++
+		case char == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case char == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case char == '"':
+			t, end, err := phraseToken(runes, i, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i = end
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			prefix, rest, found := strings.Cut(word, ":")
+			if !found || !isLetters(prefix) {
+				t, err := wordToken(word, start)
+				if err != nil {
+					return nil, err
+				}
+				t.end = i
+				tokens = append(tokens, t)
+				continue
+			}
+
+			field, ok := fields[strings.ToLower(prefix)]
+			if !ok {
+				return nil, &ParseError{Pos: start, Msg: fmt.Sprintf("unknown field %q", prefix)}
+			}
+
+			switch {
+			case rest != "":
+				tokens = append(tokens, token{kind: tokenWord, text: rest, field: field, pos: start, end: i})
+			case i < len(runes) && runes[i] == '"':
+				t, end, err := phraseToken(runes, i, start)
+				if err != nil {
+					return nil, err
+				}
+				t.field = field
+				tokens = append(tokens, t)
+				i = end
+			default:
+				return nil, &ParseError{Pos: start, Msg: fmt.Sprintf("expected word or phrase after %q", word)}
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// phraseToken читает фразу, открывающая кавычка которой стоит в позиции quote.
+// Возвращает лексему с позицией pos и позицию после закрывающей кавычки
+func phraseToken(runes []rune, quote, pos int) (token, int, error) {
+	// Фраза продолжается до закрывающей кавычки
+	end := quote + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end == len(runes) {
+		return token{}, 0, &ParseError{Pos: quote, Msg: "unclosed quote"}
+	}
+
+	return token{kind: tokenPhrase, text: string(runes[quote+1 : end]), pos: pos, end: end + 1}, end + 1, nil
+}
+
+// isLetters проверяет, что строка непустая и состоит только из букв
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// wordToken определяет, является ли слово оператором
+func wordToken(word string, pos int) (token, error) {
+	upper := strings.ToUpper(word)
+
+	switch upper {
+	case "AND":
+		return token{kind: tokenAnd, text: upper, pos: pos}, nil
+	case "OR":
+		return token{kind: tokenOr, text: upper, pos: pos}, nil
+	case "NOT":
+		return token{kind: tokenNot, text: upper, pos: pos}, nil
+	}
+
+	if distance, found := strings.CutPrefix(upper, "NEAR/"); found {
+		n, err := strconv.Atoi(distance)
+		if err != nil || n < 1 {
+			return token{}, &ParseError{Pos: pos, Msg: fmt.Sprintf("invalid NEAR distance %q", distance)}
+		}
+		return token{kind: tokenNear, text: upper, pos: pos, distance: n}, nil
+	}
+
+	return token{kind: tokenWord, text: word, pos: pos}, nil
+}
+
+// parser рекурсивный нисходящий разбор списка лексем
+type parser struct {
+	tokens   []token
+	pos      int
+	analyzer *analysis.Analyzer
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Query, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (Query, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+	}
+
+	return p.parseNear()
+}
+
+func (p *parser) parseNear() (Query, error) {
+	left, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenNear {
+		operator := p.next()
+		if !isPositional(left) {
+			return nil, &ParseError{Pos: operator.pos, Msg: fmt.Sprintf("%s operands must be words or phrases", operator.text)}
+		}
+
+		rightToken := p.peek()
+		right, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		if !isPositional(right) {
+			return nil, &ParseError{Pos: rightToken.pos, Msg: fmt.Sprintf("%s operands must be words or phrases", operator.text)}
+		}
+		if fieldOf(left) != fieldOf(right) {
+			return nil, &ParseError{Pos: rightToken.pos, Msg: fmt.Sprintf("%s operands must be in the same field", operator.text)}
+		}
+
+		left = Near{Left: left, Right: right, Distance: operator.distance}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAtom() (Query, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenWord:
+		if strings.Contains(t.text, "*") {
+			return wildcard(t)
+		}
+		if word, distance, found := strings.Cut(t.text, "~"); found {
+			return p.fuzzy(t, word, distance)
+		}
+
+		// Слово через дефис анализатор может разбить на несколько токенов, тогда ищем их как фразу
+		lemmas := p.analyzer.Lemmas(t.text)
+		switch len(lemmas) {
+		case 0:
+			return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("word %q is not indexed: it is dropped by the analyzer %q", t.text, p.analyzer.Config())}
+		case 1:
+			return Term{Field: t.field, Lemma: lemmas[0], Pos: t.pos}, nil
+		default:
+			return Phrase{Field: t.field, Lemmas: lemmas, Pos: t.pos}, nil
+		}
+	case tokenPhrase:
+		if strings.Contains(t.text, "*") {
+			return nil, &ParseError{Pos: t.pos, Msg: "wildcards are not supported in phrases"}
+		}
+
+		lemmas := p.analyzer.Lemmas(t.text)
+		if len(lemmas) == 0 {
+			return nil, &ParseError{Pos: t.pos, Msg: "empty phrase"}
+		}
+		return Phrase{Field: t.field, Lemmas: lemmas, Pos: t.pos}, nil
+	case tokenLParen:
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &ParseError{Pos: t.pos, Msg: "unclosed parenthesis"}
+		}
+		return q, nil
+	default:
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected word, phrase or \"(\", got %s", t.describe())}
+	}
+}
+
+// wildcard строит шаблон слова. Шаблон приводится к нижнему регистру и должен содержать хотя бы одну букву или цифру
+func wildcard(t token) (Query, error) {
+	pattern := strings.ToLower(t.text)
+	if !strings.ContainsFunc(pattern, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("wildcard %q must contain a letter or a digit", t.text)}
+	}
+
+	return Wildcard{Field: t.field, Pattern: pattern, Pos: t.pos}, nil
+}
+
+// fuzzy строит нечеткий поиск слова word с расстоянием distance, записанным после "~"
+func (p *parser) fuzzy(t token, word, distance string) (Query, error) {
+	n := MaxFuzzyDistance
+	if distance != "" {
+		var err error
+		if n, err = strconv.Atoi(distance); err != nil || n < 0 || n > MaxFuzzyDistance {
+			return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("invalid fuzzy distance %q: must be from 0 to %d", distance, MaxFuzzyDistance)}
+		}
+	}
+
+	lemmas := p.analyzer.Lemmas(word)
+	if len(lemmas) != 1 {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("fuzzy search needs exactly one indexed word, got %q", word)}
+	}
+
+	return Fuzzy{Field: t.field, Lemma: lemmas[0], Distance: n, Pos: t.pos}, nil
+}
+
+// isPositional проверяет, что для результата узла известны позиции слов на странице
+func isPositional(q Query) bool {
+	switch q.(type) {
+	case Term, Phrase, Wildcard, Fuzzy, Near:
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldOf возвращает поле, в котором ищется позиционный узел. Пустое поле и body - одно и то же поле
+func fieldOf(q Query) string {
+	var field string
+	switch q := q.(type) {
+	case Term:
+		field = q.Field
+	case Phrase:
+		field = q.Field
+	case Wildcard:
+		field = q.Field
+	case Fuzzy:
+		field = q.Field
+	case Near:
+		return fieldOf(q.Left)
+	}
+
+	return QueryField(field)
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"errors"
+	"oip-course/internal/analysis"
+	"strings"
+	"testing"
+)
+
+// newAnalyzer возвращает анализатор без лемматизатора, чтобы леммы в тестах совпадали со словами
+func newAnalyzer(t *testing.T) *analysis.Analyzer {
+	t.Helper()
+
+	analyzer, err := analysis.New(analysis.Config{
+		Tokenizer: "standard",
+		Filters:   []string{"lowercase", "script:russian", "min_length:3", "stopwords:ru"},
+	})
+	if err != nil {
+		t.Fatalf("analysis.New: %v", err)
+	}
+
+	return analyzer
+}
+
+func TestParse(t *testing.T) {
+	analyzer := newAnalyzer(t)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"ген", "ген"},
+		{"  Ген  ", "ген"},
+		{"ген AND клетка OR белок", "((ген AND клетка) OR белок)"},
+		{"ген OR клетка AND белок", "(ген OR (клетка AND белок))"},
+		{"ген and клетка or белок", "((ген AND клетка) OR белок)"},
+		{"(ген OR клетка) AND белок", "((ген OR клетка) AND белок)"},
+		{"NOT ген AND клетка", "(NOT ген AND клетка)"},
+		{"NOT NOT ген", "NOT NOT ген"},
+		{"ген AND NOT (клетка OR белок)", "(ген AND NOT (клетка OR белок))"},
+		{`"Черная дыра"`, `"черная дыра"`},
+		{`title:"черная дыра" NEAR/3 title:звезда`, `(title:"черная дыра" NEAR/3 title:звезда)`},
+		{"ген NEAR/2 клетка NEAR/5 белок", "((ген NEAR/2 клетка) NEAR/5 белок)"},
+		{"body:ген near/2 клетка", "(body:ген NEAR/2 клетка)"},
+		{"tag:биология OR Title:эволюция", "(tags:биология OR title:эволюция)"},
+		{"Генет* OR *фаг", "(генет* OR *фаг)"},
+		{"ген*аза NEAR/2 белок", "(ген*аза NEAR/2 белок)"},
+		{"гинетика~1", "гинетика~1"},
+		{"lead:гинетика~", "lead:гинетика~2"},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.query, analyzer)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.query, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	analyzer := newAnalyzer(t)
+
+	tests := []struct {
+		query string
+		pos   int    // Позиция ошибки в символах
+		msg   string // Начало сообщения
+	}{
+		{"", 0, "empty query"},
+		{"   ", 0, "empty query"},
+		{"ген AND", 7, "expected word"},
+		{"эволюция AND )", 13, "expected word"}, // Позиция считается в символах, а не в байтах
+		{"ген клетка", 4, "expected operator before"},
+		{"(ген OR клетка", 0, "unclosed parenthesis"},
+		{"ген)", 3, "unexpected closing parenthesis"},
+		{`ген AND "черная дыра`, 8, "unclosed quote"},
+		{`ген AND title:"черная`, 14, "unclosed quote"},
+		{"author:ген", 0, "unknown field"},
+		{"ген AND title:", 8, "expected word or phrase after"},
+		{"ген NEAR/0 клетка", 4, "invalid NEAR distance"},
+		{"ген NEAR/x клетка", 4, "invalid NEAR distance"},
+		{"(ген OR клетка) NEAR/2 белок", 16, "NEAR/2 operands must be words or phrases"},
+		{"ген NEAR/2 NOT клетка", 11, "expected word"},
+		{"title:ген NEAR/2 клетка", 17, "NEAR/2 operands must be in the same field"},
+		{"ген AND и", 8, "word \"и\" is not indexed"},
+		{`ген OR "и на"`, 7, "empty phrase"},
+		{`"ген*"`, 0, "wildcards are not supported in phrases"},
+		{"ген AND **", 8, "wildcard \"**\" must contain a letter or a digit"},
+		{"ген~3", 0, "invalid fuzzy distance"},
+		{"ген AND и~1", 8, "fuzzy search needs exactly one indexed word"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.query, analyzer)
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) error = %v, want *ParseError", tt.query, err)
+			continue
+		}
+		if !errors.Is(err, ErrWrongQuery) {
+			t.Errorf("Parse(%q) error does not wrap ErrWrongQuery", tt.query)
+		}
+		if parseErr.Pos != tt.pos || !strings.HasPrefix(parseErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) error at %d: %q, want at %d: %q", tt.query, parseErr.Pos, parseErr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
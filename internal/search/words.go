@@ -0,0 +1,55 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Word слово запроса, которое ищется в индексе как есть или нечетко. Операторы и шаблоны словами не считаются
+type Word struct {
+	Text  string // Слово без префикса поля и суффикса "~n"
+	Field string // Поле документа, пустое - текст статьи
+	Start int    // Позиция начала слова в запросе (в символах)
+	End   int    // Позиция после слова
+}
+
+// Words возвращает слова запроса, в том числе слова фраз, в порядке следования.
+// Позиции слов позволяют заменить в запросе отдельные слова, например при исправлении опечаток
+func Words(query string) ([]Word, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var words []Word
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenWord:
+			if strings.Contains(t.text, "*") {
+				continue
+			}
+
+			text, _, _ := strings.Cut(t.text, "~")
+			start := t.end - len([]rune(t.text))
+			words = append(words, Word{Text: text, Field: t.field, Start: start, End: start + len([]rune(text))})
+		case tokenPhrase:
+			// Текст фразы стоит перед закрывающей кавычкой
+			offset := t.end - 1 - len([]rune(t.text))
+			runes := []rune(t.text)
+			for i := 0; i < len(runes); {
+				if unicode.IsSpace(runes[i]) {
+					i++
+					continue
+				}
+
+				start := i
+				for i < len(runes) && !unicode.IsSpace(runes[i]) {
+					i++
+				}
+				words = append(words, Word{Text: string(runes[start:i]), Field: t.field, Start: offset + start, End: offset + i})
+			}
+		}
+	}
+
+	return words, nil
+}
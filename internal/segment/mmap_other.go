@@ -0,0 +1,15 @@
+//go:build !unix
+
+package segment
+
+import "os"
+
+// mapFile читает файл в память целиком на системах без mmap
+func mapFile(filename string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package segment
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mapFile отображает файл в память только для чтения
+func mapFile(filename string) ([]byte, func() error, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Пустой файл отобразить нельзя, а сегментом он все равно не является
+	if info.Size() == 0 {
+		return nil, nil, fmt.Errorf("%s: %w", filename, ErrInvalidSegment)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,392 @@
+// Package segment реализует бинарный формат инвертированного индекса.
+//
+// Сегмент состоит из заголовка, метаданных индекса, списков вхождений, словаря лемм, таблицы смещений словаря и таблицы документов.
+// Все числа фиксированной длины записываются в little-endian:
+//
+//	заголовок (40 байт):
+//	  magic          [4]byte "OIPX"
+//	  version        uint32
+//	  termCount      uint32
+//	  docCount       uint32
+//	  fieldCount     uint32 - кол-во полей документа, models.Fields
+//	  reserved       uint32
+//	  termTableStart uint64 - начало таблицы смещений словаря
+//	  docTableStart  uint64 - начало таблицы документов
+//	метаданные индекса models.Metadata: uvarint длина, JSON
+//	списки вхождений: для каждой леммы
+//	  uvarint кол-во страниц, затем для каждой страницы
+//	  uvarint разница с номером предыдущей страницы, uvarint кол-во позиций (частота леммы),
+//	  uvarint разницы между соседними позициями
+//	словарь: для каждой леммы в порядке возрастания ключа "поле\x00лемма"
+//	  uvarint длина ключа, ключ, uvarint смещение списка вхождений
+//	таблица смещений словаря: termCount * uint32 смещений записей словаря
+//	таблица документов: для каждой страницы по возрастанию номеров
+//	  uint32 номер страницы, fieldCount * uint32 кол-во токенов в полях в порядке models.Fields
+//
+// Таблица смещений позволяет искать лемму бинарным поиском, не разбирая словарь целиком
+package segment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"oip-course/internal/models"
+	"os"
+	"sort"
+)
+
+const (
+	magic      = "OIPX"
+	version    = 4
+	headerSize = 40
+)
+
+// ErrInvalidSegment файл не является сегментом индекса или поврежден
+var ErrInvalidSegment = errors.New("invalid index segment")
+
+// Write записывает индекс в файл сегмента. Файл заменяется атомарно
+func Write(filename string, index models.Index) error {
+	var (
+		postings bytes.Buffer
+		keys     []string
+		offsets  = make(map[string]int)
+	)
+
+	metadataJSON, err := json.Marshal(index.Metadata())
+	if err != nil {
+		return err
+	}
+	metadata := binary.AppendUvarint(nil, uint64(len(metadataJSON)))
+	metadata = append(metadata, metadataJSON...)
+	postingsStart := headerSize + len(metadata)
+
+	// Ключ словаря "поле\x00лемма" сортирует леммы по полям, а внутри поля - по алфавиту
+	for _, field := range models.Fields {
+		for _, lemma := range index.Terms(field) {
+			key := field + "\x00" + lemma
+			keys = append(keys, key)
+			offsets[key] = postingsStart + postings.Len()
+			encodePostings(&postings, index.Postings(field, lemma))
+		}
+	}
+	sort.Strings(keys)
+
+	var dictionary bytes.Buffer
+	dictionaryStart := postingsStart + postings.Len()
+	termTable := make([]uint32, 0, len(keys))
+	for _, key := range keys {
+		termTable = append(termTable, uint32(dictionaryStart+dictionary.Len()))
+		dictionary.Write(binary.AppendUvarint(nil, uint64(len(key))))
+		dictionary.WriteString(key)
+		dictionary.Write(binary.AppendUvarint(nil, uint64(offsets[key])))
+	}
+
+	pages := index.AllPages()
+	docEntrySize := 4 * (1 + len(models.Fields))
+	termTableStart := dictionaryStart + dictionary.Len()
+	docTableStart := termTableStart + 4*len(termTable)
+	if size := docTableStart + docEntrySize*len(pages); size > math.MaxUint32 {
+		return fmt.Errorf("index segment is too large: %d bytes", size)
+	}
+
+	var file bytes.Buffer
+	file.WriteString(magic)
+	file.Write(binary.LittleEndian.AppendUint32(nil, version))
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(keys))))
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(pages))))
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(models.Fields))))
+	file.Write(binary.LittleEndian.AppendUint32(nil, 0))
+	file.Write(binary.LittleEndian.AppendUint64(nil, uint64(termTableStart)))
+	file.Write(binary.LittleEndian.AppendUint64(nil, uint64(docTableStart)))
+	file.Write(metadata)
+	file.Write(postings.Bytes())
+	file.Write(dictionary.Bytes())
+	for _, offset := range termTable {
+		file.Write(binary.LittleEndian.AppendUint32(nil, offset))
+	}
+	for _, page := range pages {
+		file.Write(binary.LittleEndian.AppendUint32(nil, uint32(page)))
+		for _, field := range models.Fields {
+			file.Write(binary.LittleEndian.AppendUint32(nil, uint32(index.DocLength(field, page))))
+		}
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, file.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// encodePostings записывает позиции леммы по страницам, номера страниц и позиции кодируются разницами
+func encodePostings(buf *bytes.Buffer, postings map[int][]int) {
+	pages := make([]int, 0, len(postings))
+	for page := range postings {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	buf.Write(binary.AppendUvarint(nil, uint64(len(pages))))
+
+	previousPage := 0
+	for _, page := range pages {
+		positions := append([]int(nil), postings[page]...)
+		sort.Ints(positions)
+
+		buf.Write(binary.AppendUvarint(nil, uint64(page-previousPage)))
+		buf.Write(binary.AppendUvarint(nil, uint64(len(positions))))
+
+		previousPosition := 0
+		for _, position := range positions {
+			buf.Write(binary.AppendUvarint(nil, uint64(position-previousPosition)))
+			previousPosition = position
+		}
+
+		previousPage = page
+	}
+}
+
+// Segment открытый сегмент индекса. Леммы ищутся в словаре по запросу, списки вхождений разбираются при обращении
+type Segment struct {
+	data      []byte
+	release   func() error
+	termCount int
+	termTable []byte
+	metadata  models.Metadata
+	pages     []int
+	lengths   map[string]map[int]int // поле -> номер страницы -> кол-во токенов в поле
+
+	dictionaries models.DictionaryCache // Словари лемм полей в памяти, строятся при первом поиске по шаблону
+}
+
+// Open открывает файл сегмента, отображая его в память
+func Open(filename string) (*Segment, error) {
+	data, release, err := mapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSegment(data)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	s.release = release
+
+	return s, nil
+}
+
+// newSegment проверяет заголовок и границы таблиц сегмента
+func newSegment(data []byte) (*Segment, error) {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return nil, ErrInvalidSegment
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSegment, v)
+	}
+
+	termCount := uint64(binary.LittleEndian.Uint32(data[8:]))
+	docCount := uint64(binary.LittleEndian.Uint32(data[12:]))
+	fieldCount := binary.LittleEndian.Uint32(data[16:])
+	termTableStart := binary.LittleEndian.Uint64(data[24:])
+	docTableStart := binary.LittleEndian.Uint64(data[32:])
+
+	if fieldCount != uint32(len(models.Fields)) {
+		return nil, fmt.Errorf("%w: segment has %d fields, expected %d", ErrInvalidSegment, fieldCount, len(models.Fields))
+	}
+
+	metadataLen, n := binary.Uvarint(data[headerSize:])
+	if n <= 0 || metadataLen > uint64(len(data)-headerSize-n) {
+		return nil, fmt.Errorf("%w: corrupted metadata", ErrInvalidSegment)
+	}
+	postingsStart := uint64(headerSize+n) + metadataLen
+
+	var metadata models.Metadata
+	if err := json.Unmarshal(data[headerSize+n:postingsStart], &metadata); err != nil {
+		return nil, fmt.Errorf("%w: corrupted metadata: %v", ErrInvalidSegment, err)
+	}
+
+	docEntrySize := 4 * (1 + uint64(fieldCount))
+	if termTableStart < postingsStart || termTableStart+4*termCount != docTableStart || docTableStart+docEntrySize*docCount != uint64(len(data)) {
+		return nil, fmt.Errorf("%w: corrupted tables", ErrInvalidSegment)
+	}
+
+	// Таблица документов небольшая, разбираем ее сразу
+	pages := make([]int, docCount)
+	lengths := make(map[string]map[int]int, fieldCount)
+	for _, field := range models.Fields {
+		lengths[field] = make(map[int]int, docCount)
+	}
+	for i := range pages {
+		entry := data[docTableStart+docEntrySize*uint64(i):]
+		pages[i] = int(binary.LittleEndian.Uint32(entry))
+		for j, field := range models.Fields {
+			lengths[field][pages[i]] = int(binary.LittleEndian.Uint32(entry[4*(j+1):]))
+		}
+	}
+
+	return &Segment{
+		data:      data,
+		termCount: int(termCount),
+		termTable: data[termTableStart:docTableStart],
+		metadata:  metadata,
+		pages:     pages,
+		lengths:   lengths,
+	}, nil
+}
+
+// Close освобождает память сегмента. После закрытия сегментом пользоваться нельзя
+func (s *Segment) Close() error {
+	if s.release == nil {
+		return nil
+	}
+
+	err := s.release()
+	s.data, s.termTable, s.release = nil, nil, nil
+
+	return err
+}
+
+// AllPages возвращает отсортированный список всех страниц индекса
+func (s *Segment) AllPages() []int {
+	return s.pages
+}
+
+// Metadata возвращает заголовок индекса
+func (s *Segment) Metadata() models.Metadata {
+	return s.metadata
+}
+
+// DocLength возвращает кол-во токенов в поле страницы
+func (s *Segment) DocLength(field string, page int) int {
+	return s.lengths[field][page]
+}
+
+// Postings возвращает позиции леммы в поле по номерам страниц
+func (s *Segment) Postings(field string, lemma string) map[int][]int {
+	key := field + "\x00" + lemma
+
+	i := s.search(key)
+	if i == s.termCount {
+		return nil
+	}
+
+	entryKey, offset, ok := s.entry(i)
+	if !ok || entryKey != key {
+		return nil
+	}
+
+	return s.decodePostings(offset)
+}
+
+// Terms возвращает отсортированный список лемм поля
+func (s *Segment) Terms(field string) []string {
+	prefix := field + "\x00"
+
+	var terms []string
+	for i := s.search(prefix); i < s.termCount; i++ {
+		key, _, ok := s.entry(i)
+		if !ok || len(key) < len(prefix) || key[:len(prefix)] != prefix {
+			break
+		}
+		terms = append(terms, key[len(prefix):])
+	}
+
+	return terms
+}
+
+// Dictionary возвращает отсортированный словарь лемм поля
+func (s *Segment) Dictionary(field string) *models.TermDictionary {
+	return s.dictionaries.Get(field, func() []string { return s.Terms(field) })
+}
+
+// search возвращает номер первой записи словаря с ключом не меньше key
+func (s *Segment) search(key string) int {
+	return sort.Search(s.termCount, func(i int) bool {
+		entryKey, _, ok := s.entry(i)
+		return !ok || entryKey >= key
+	})
+}
+
+// entry разбирает запись словаря с номером i: ключ и смещение списка вхождений
+func (s *Segment) entry(i int) (string, uint64, bool) {
+	start := uint64(binary.LittleEndian.Uint32(s.termTable[4*i:]))
+	if start >= uint64(len(s.data)) {
+		return "", 0, false
+	}
+	data := s.data[start:]
+
+	keyLen, n := binary.Uvarint(data)
+	if n <= 0 || keyLen > uint64(len(data)-n) {
+		return "", 0, false
+	}
+	key := string(data[n : n+int(keyLen)])
+	data = data[n+int(keyLen):]
+
+	offset, n := binary.Uvarint(data)
+	if n <= 0 {
+		return "", 0, false
+	}
+
+	return key, offset, true
+}
+
+// decodePostings разбирает список вхождений, начинающийся со смещения offset.
+// Для поврежденного списка возвращаются вхождения, разобранные до места повреждения
+func (s *Segment) decodePostings(offset uint64) map[int][]int {
+	if offset >= uint64(len(s.data)) {
+		return nil
+	}
+	r := &reader{data: s.data[offset:]}
+
+	pageCount := r.uvarint()
+	postings := make(map[int][]int, min(pageCount, uint64(len(s.pages))))
+
+	page := 0
+	for range pageCount {
+		page += int(r.uvarint())
+		positionCount := r.uvarint()
+		if r.err != nil || positionCount > uint64(len(r.data)) {
+			break
+		}
+
+		positions := make([]int, 0, positionCount)
+		position := 0
+		for range positionCount {
+			position += int(r.uvarint())
+			positions = append(positions, position)
+		}
+		if r.err != nil {
+			break
+		}
+
+		postings[page] = positions
+	}
+
+	return postings
+}
+
+// reader последовательно читает uvarint числа, запоминая первую ошибку
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = ErrInvalidSegment
+		return 0
+	}
+	r.data = r.data[n:]
+
+	return value
+}
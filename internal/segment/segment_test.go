@@ -0,0 +1,145 @@
+package segment
+
+import (
+	"errors"
+	"oip-course/internal/analysis"
+	"oip-course/internal/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testIndex строит небольшой индекс по нескольким полям, страница 9 есть только в таблице документов
+func testIndex() *models.InvertedIndex {
+	index := models.NewInvertedIndex(make(map[string]map[string]map[int][]int), make(map[string]map[int]int))
+
+	for _, p := range []struct {
+		field    string
+		lemma    string
+		page     int
+		position int
+	}{
+		{models.FieldTitle, "ген", 1, 0},
+		{models.FieldTitle, "клетка", 2, 1},
+		{models.FieldBody, "ген", 1, 3},
+		{models.FieldBody, "ген", 1, 17},
+		{models.FieldBody, "ген", 1, 400},
+		{models.FieldBody, "ген", 5, 2},
+		{models.FieldBody, "белок", 2, 0},
+		{models.FieldBody, "абиогенез", 1_000_000, 70_000},
+		{models.FieldTags, "генетика", 1, 0},
+		{models.FieldTags, "генетика", 2, 0},
+	} {
+		index.Add(p.field, p.lemma, p.page, p.position)
+	}
+
+	for _, page := range []int{1, 2, 5, 9, 1_000_000} {
+		for i, field := range models.Fields {
+			index.SetDocLength(field, page, page%100+i)
+		}
+	}
+
+	index.SetMetadata(models.Metadata{
+		Version:  models.MetadataVersion,
+		Analyzer: analysis.DefaultConfig,
+		DocCount: 5,
+		BuiltAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Sources:  map[string]string{"pages/1.txt": "abc"},
+	})
+
+	return index
+}
+
+func TestRoundTrip(t *testing.T) {
+	index := testIndex()
+	filename := filepath.Join(t.TempDir(), "index.idx")
+	if err := Write(filename, index); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	s, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	if got, want := s.AllPages(), index.AllPages(); !slices.Equal(got, want) {
+		t.Errorf("AllPages() = %v, want %v", got, want)
+	}
+	if got, want := s.Metadata(), index.Metadata(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Metadata() = %+v, want %+v", got, want)
+	}
+
+	for _, field := range models.Fields {
+		if got, want := s.Terms(field), index.Terms(field); !slices.Equal(got, want) && len(got)+len(want) > 0 {
+			t.Errorf("Terms(%s) = %v, want %v", field, got, want)
+		}
+
+		for _, lemma := range append(index.Terms(field), "нет", "") {
+			if got, want := s.Postings(field, lemma), index.Postings(field, lemma); !reflect.DeepEqual(got, want) {
+				t.Errorf("Postings(%s, %q) = %v, want %v", field, lemma, got, want)
+			}
+		}
+
+		for _, page := range append(index.AllPages(), 3) {
+			if got, want := s.DocLength(field, page), index.DocLength(field, page); got != want {
+				t.Errorf("DocLength(%s, %d) = %d, want %d", field, page, got, want)
+			}
+		}
+	}
+
+	if got, want := s.Dictionary(models.FieldBody).Terms(), index.Terms(models.FieldBody); !slices.Equal(got, want) {
+		t.Errorf("Dictionary(body).Terms() = %v, want %v", got, want)
+	}
+}
+
+func TestInvalidSegment(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "index.idx")
+	if err := Write(filename, testIndex()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badMagic := slices.Clone(data)
+	copy(badMagic, "ABCD")
+	badVersion := slices.Clone(data)
+	badVersion[4]++
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"header", data[:headerSize-1]},
+		{"metadata", data[:headerSize+10]},
+		{"tables", data[:len(data)/2]},
+		{"last byte", data[:len(data)-1]},
+		{"magic", badMagic},
+		{"version", badVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broken := filepath.Join(dir, tt.name+".idx")
+			if err := os.WriteFile(broken, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := Open(broken)
+			if err == nil {
+				s.Close()
+			}
+			if !errors.Is(err, ErrInvalidSegment) {
+				t.Errorf("Open = %v, want ErrInvalidSegment", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package spelling
+
+import (
+	"oip-course/internal/search"
+	"strings"
+)
+
+// layout соответствие клавиш английской раскладки QWERTY русской ЙЦУКЕН
+var layout = map[rune]rune{
+	'q': 'й', 'w': 'ц', 'e': 'у', 'r': 'к', 't': 'е', 'y': 'н', 'u': 'г', 'i': 'ш', 'o': 'щ', 'p': 'з', '[': 'х', ']': 'ъ',
+	'a': 'ф', 's': 'ы', 'd': 'в', 'f': 'а', 'g': 'п', 'h': 'р', 'j': 'о', 'k': 'л', 'l': 'д', ';': 'ж', '\'': 'э',
+	'z': 'я', 'x': 'ч', 'c': 'с', 'v': 'м', 'b': 'и', 'n': 'т', 'm': 'ь', ',': 'б', '.': 'ю', '`': 'ё',
+}
+
+// transliteration сочетания латинских букв и соответствующие им русские буквы. Сочетания проверяются
+// от длинных к коротким, "y" после гласной и "e" в начале слова разбираются отдельно
+var transliteration = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"},
+	{"sch", "щ"},
+	{"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"}, {"sh", "ш"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"}, {"ju", "ю"}, {"ja", "я"},
+	{"a", "а"}, {"b", "б"}, {"v", "в"}, {"g", "г"}, {"d", "д"}, {"e", "е"}, {"z", "з"}, {"i", "и"}, {"j", "й"},
+	{"k", "к"}, {"l", "л"}, {"m", "м"}, {"n", "н"}, {"o", "о"}, {"p", "п"}, {"r", "р"}, {"s", "с"}, {"t", "т"},
+	{"u", "у"}, {"f", "ф"}, {"h", "х"}, {"c", "ц"}, {"w", "в"}, {"x", "кс"}, {"q", "к"}, {"y", "ы"}, {"'", "ь"},
+}
+
+// FromLatinLayout возвращает слово, набранное в английской раскладке вместо русской ("uty" - "ген").
+// false - в слове есть символы, которых нет на клавишах русских букв
+func FromLatinLayout(word string) (string, bool) {
+	var converted strings.Builder
+	for _, r := range strings.ToLower(word) {
+		c, ok := layout[r]
+		if !ok {
+			return "", false
+		}
+		converted.WriteRune(c)
+	}
+
+	return converted.String(), word != ""
+}
+
+// Transliterate возвращает слово, записанное латинской транслитерацией ("genetika" - "генетика").
+// false - в слове есть символы, кроме латинских букв и апострофа
+func Transliterate(word string) (string, bool) {
+	word = strings.ToLower(word)
+	for _, r := range word {
+		if (r < 'a' || r > 'z') && r != '\'' {
+			return "", false
+		}
+	}
+
+	var converted []rune
+	for rest := word; rest != ""; {
+		switch {
+		case rest[0] == 'e' && len(converted) == 0:
+			converted = append(converted, 'э')
+			rest = rest[1:]
+		case rest[0] == 'y' && len(converted) > 0 && isVowel(converted[len(converted)-1]) && !startsIotated(rest):
+			converted = append(converted, 'й')
+			rest = rest[1:]
+		default:
+			for _, t := range transliteration {
+				if strings.HasPrefix(rest, t.latin) {
+					converted = append(converted, []rune(t.cyrillic)...)
+					rest = rest[len(t.latin):]
+					break
+				}
+			}
+		}
+	}
+
+	return string(converted), word != ""
+}
+
+// isVowel проверяет, что буква русская гласная
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеёиоуыэюя", r)
+}
+
+// startsIotated проверяет, что строка начинается с сочетания "y" и гласной, которое записывает одну йотированную букву
+func startsIotated(s string) bool {
+	return len(s) > 1 && strings.ContainsRune("uaoe", rune(s[1]))
+}
+
+// Rewrite переводит в кириллицу слова запроса, набранные в английской раскладке или транслитом,
+// если после перевода их лемма есть в индексе, а до перевода - нет. Из вариантов перевода выбирается тот,
+// лемма которого встречается на большем кол-ве страниц. false - переводить нечего
+func (c *Corrector) Rewrite(query string) (string, bool) {
+	words, err := search.Words(query)
+	if err != nil {
+		return "", false
+	}
+
+	var replacements []replacement
+	for _, word := range words {
+		if c.indexed(word.Field, word.Text) {
+			continue
+		}
+
+		best, bestDF := "", 0
+		for _, convert := range []func(string) (string, bool){FromLatinLayout, Transliterate} {
+			converted, ok := convert(word.Text)
+			if !ok {
+				continue
+			}
+
+			lemmas := c.analyzer.Lemmas(converted)
+			if len(lemmas) != 1 {
+				continue
+			}
+			if df := len(c.index.Postings(search.QueryField(word.Field), lemmas[0])); df > bestDF {
+				best, bestDF = converted, df
+			}
+		}
+
+		if best != "" {
+			replacements = append(replacements, replacement{word: word, text: best})
+		}
+	}
+
+	return replace(query, replacements)
+}
+
+// Retry выполняет поиск find по запросу, а если он ничего не нашел или запрос не разобран, повторяет поиск
+// по запросу, переведенному Rewrite. Возвращает результаты повторного поиска и переведенный запрос, если повтор
+// что-то нашел, иначе результаты и ошибку исходного поиска с пустым переведенным запросом.
+// Это функция, а не метод Corrector, потому что методы в Go не могут иметь параметров типа
+func Retry[S ~[]E, E any](c *Corrector, query string, find func(query string) (S, error)) (S, string, error) {
+	results, err := find(query)
+	if err == nil && len(results) > 0 {
+		return results, "", nil
+	}
+
+	if rewritten, ok := c.Rewrite(query); ok {
+		if rewrittenResults, rewrittenErr := find(rewritten); rewrittenErr == nil && len(rewrittenResults) > 0 {
+			return rewrittenResults, rewritten, nil
+		}
+	}
+
+	return results, "", err
+}
+
+// indexed проверяет, что все леммы слова есть в поле индекса. Слова, которые анализатор отбрасывает целиком, не в индексе
+func (c *Corrector) indexed(field string, word string) bool {
+	lemmas := c.analyzer.Lemmas(word)
+	for _, lemma := range lemmas {
+		if len(c.index.Postings(search.QueryField(field), lemma)) == 0 {
+			return false
+		}
+	}
+
+	return len(lemmas) > 0
+}
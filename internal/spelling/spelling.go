@@ -0,0 +1,104 @@
+// Package spelling предлагает исправление опечаток в запросе по словарю индекса
+package spelling
+
+import (
+	"oip-course/internal/analysis"
+	"oip-course/internal/models"
+	"oip-course/internal/search"
+	"strings"
+)
+
+// Corrector исправляет слова запроса, которых нет в индексе. Кандидаты - термины поля на расстоянии Левенштейна
+// не больше models.AutoFuzzyDistance от леммы слова, из них выбирается ближайший, а при равном расстоянии -
+// встречающийся на большем кол-ве страниц. Вместо термина предлагается его самая частая форма в текстах
+type Corrector struct {
+	index    models.Index
+	analyzer *analysis.Analyzer
+	surface  map[string]string // Термин индекса -> самая частая форма, термины без формы не предлагаются
+}
+
+// NewCorrector создает исправление опечаток по индексу. surface - самые частые формы терминов индекса,
+// см. storage.LoadSurfaceForms
+func NewCorrector(index models.Index, analyzer *analysis.Analyzer, surface map[string]string) *Corrector {
+	return &Corrector{index: index, analyzer: analyzer, surface: surface}
+}
+
+// Suggest возвращает запрос, в котором слова, которых нет в индексе, заменены исправлениями.
+// Операторы, префиксы полей и шаблоны остаются как есть. false - исправлять нечего
+func (c *Corrector) Suggest(query string) (string, bool) {
+	words, err := search.Words(query)
+	if err != nil {
+		return "", false
+	}
+
+	var replacements []replacement
+	for _, word := range words {
+		if correction, ok := c.correct(word); ok {
+			replacements = append(replacements, replacement{word: word, text: correction})
+		}
+	}
+
+	return replace(query, replacements)
+}
+
+// correct возвращает исправление слова, если его лемм нет в поле индекса
+func (c *Corrector) correct(word search.Word) (string, bool) {
+	// Стоп-слова и слова, которые анализатор разбивает на несколько лемм, не исправляются
+	lemmas := c.analyzer.Lemmas(word.Text)
+	if len(lemmas) != 1 {
+		return "", false
+	}
+	lemma := lemmas[0]
+
+	field := search.QueryField(word.Field)
+	if len(c.index.Postings(field, lemma)) > 0 {
+		return "", false
+	}
+
+	distance := models.AutoFuzzyDistance(lemma)
+	if distance == 0 {
+		return "", false
+	}
+
+	// Кандидаты отсортированы по расстоянию, сравниваем частоты только самых близких из тех, у которых есть форма
+	best, bestDistance, bestDF := "", 0, 0
+	for _, match := range c.index.Dictionary(field).Fuzzy(lemma, distance) {
+		form, ok := c.surface[match.Term]
+		if !ok {
+			continue
+		}
+		if best != "" && match.Distance > bestDistance {
+			break
+		}
+		if df := len(c.index.Postings(field, match.Term)); df > bestDF {
+			best, bestDistance, bestDF = form, match.Distance, df
+		}
+	}
+
+	return best, best != ""
+}
+
+// replacement замена слова запроса
+type replacement struct {
+	word search.Word
+	text string
+}
+
+// replace заменяет слова запроса, замены должны идти в порядке слов. false - замен нет
+func replace(query string, replacements []replacement) (string, bool) {
+	if len(replacements) == 0 {
+		return "", false
+	}
+
+	runes := []rune(query)
+	var replaced strings.Builder
+	last := 0
+	for _, r := range replacements {
+		replaced.WriteString(string(runes[last:r.word.Start]))
+		replaced.WriteString(r.text)
+		last = r.word.End
+	}
+	replaced.WriteString(string(runes[last:]))
+
+	return replaced.String(), true
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"oip-course/internal/analysis"
+	"oip-course/internal/models"
+	"oip-course/internal/tfidf"
+	"os"
+	"strings"
+)
+
+// FormsFromStems источник групп форм слов по умолчанию: основы терминов индекса
+const FormsFromStems = "stems"
+
+// LoadWordForms возвращает группы форм слов для расширения запроса. source - FormsFromStems
+// или директория с файлами lemmas_N.txt токенайзера
+func LoadWordForms(source string, index models.Index, analyzer *analysis.Analyzer) (*models.WordForms, error) {
+	if source == FormsFromStems {
+		return stemWordForms(index, analyzer)
+	}
+
+	return loadLemmaGroups(source, analyzer.Lemma)
+}
+
+// stemWordForms группирует термины индекса, которых нет в словаре лемматизатора, по основам стеммера.
+// Лемматизатор оставляет незнакомые словарю формы ("абиогенеза", "абиогенезе") отдельными терминами, а термины
+// из словаря уже объединяют свои формы, поэтому они в группы не попадают и разные слова ("ген" и "геном")
+// не смешиваются. Для индекса, построенного стеммером или гибридом, группы не нужны и возвращается nil
+func stemWordForms(index models.Index, analyzer *analysis.Analyzer) (*models.WordForms, error) {
+	name, languages, _ := strings.Cut(analyzer.Normalizer(), ":")
+	if name != "lemmatizer" {
+		return nil, nil
+	}
+
+	stem, err := analysis.NewUnknownWordStemmer(languages)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.IndexWordForms(index, stem), nil
+}
+
+// loadLemmaGroups строит группы форм слов по файлам lemmas_N.txt токенайзера: токены, которые на любой странице
+// приводятся к одной лемме файла, считаются формами одного слова. lemma возвращает лемму индекса токена,
+// поэтому группы полезны, если токенайзер запускался с другим нормализатором, чем строился индекс
+func loadLemmaGroups(dir string, lemma func(token string) string) (*models.WordForms, error) {
+	lemmas, err := readLemmaFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, l := range lemmas {
+		groups[l.Lemma] = append(groups[l.Lemma], l.Tokens...)
+	}
+
+	return models.NewWordForms(groups, lemma), nil
+}
+
+// LoadSurfaceForms возвращает для терминов индекса их самую частую форму в текстах по файлам lemmas_N.txt
+// токенайзера: токен, который приводится к термину (lemma возвращает термин индекса токена) на наибольшем
+// кол-ве страниц, при равенстве - первый по алфавиту. Термин стеммера - основа, а лемма не всегда совпадает
+// со словом из текста, поэтому пользователю показываются формы, а не термины
+func LoadSurfaceForms(dir string, lemma func(token string) string) (map[string]string, error) {
+	lemmas, err := readLemmaFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]int) // Токен -> кол-во страниц, на которых он встречается
+	for _, l := range lemmas {
+		for _, token := range l.Tokens {
+			pages[token]++
+		}
+	}
+
+	forms := make(map[string]string)
+	for token, n := range pages {
+		term := lemma(token)
+		if term == "" {
+			continue
+		}
+
+		best, ok := forms[term]
+		if !ok || n > pages[best] || n == pages[best] && token < best {
+			forms[term] = token
+		}
+	}
+
+	return forms, nil
+}
+
+// readLemmaFiles читает леммы всех страниц из файлов lemmas_N.txt директории
+func readLemmaFiles(dir string) ([]tfidf.LemmaTokens, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var lemmas []tfidf.LemmaTokens
+	for _, item := range items {
+		page, err := tfidf.ReadLemmas(dir + "/" + item.Name())
+		if err != nil {
+			return nil, err
+		}
+		lemmas = append(lemmas, page...)
+	}
+
+	return lemmas, nil
+}
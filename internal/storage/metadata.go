@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"oip-course/internal/analysis"
+	"oip-course/internal/models"
+	"os"
+	"sort"
+)
+
+// HashFiles возвращает SHA-256 содержимого файлов по их путям
+func HashFiles(paths []string) (map[string]string, error) {
+	hashes := make(map[string]string, len(paths))
+	for _, path := range paths {
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		hashes[path] = hash
+	}
+
+	return hashes, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyIndex сверяет заголовок индекса с тем, как поиск разбирает запросы и что лежит на диске.
+// Ошибка означает, что запросы разбираются не тем конвейером анализа, которым строился индекс,
+// с allowMismatch это расхождение становится предупреждением (флаг -allow-mismatch команд поиска).
+// Предупреждения описывают расхождения, при которых поиск работает, но выдача может устареть:
+// исходные файлы изменились после построения индекса или кол-во страниц не совпадает с заголовком
+func VerifyIndex(index models.Index, config analysis.Config, allowMismatch bool) (warnings []string, err error) {
+	metadata := index.Metadata()
+	if err := metadata.CheckAnalyzer(config); err != nil {
+		if !allowMismatch {
+			return nil, fmt.Errorf("%w (rebuild the index or pass -allow-mismatch)", err)
+		}
+		warnings = append(warnings, err.Error())
+	}
+
+	if pages := len(index.AllPages()); pages != metadata.DocCount {
+		warnings = append(warnings, fmt.Sprintf("index has %d pages, but its metadata records %d", pages, metadata.DocCount))
+	}
+
+	paths := make([]string, 0, len(metadata.Sources))
+	for path := range metadata.Sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		hash, err := hashFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			warnings = append(warnings, fmt.Sprintf("source %s was removed after the index was built", path))
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("source %s: %v", path, err))
+		case hash != metadata.Sources[path]:
+			warnings = append(warnings, fmt.Sprintf("source %s changed after the index was built at %s", path, metadata.BuiltAt.Format("2006-01-02 15:04:05")))
+		}
+	}
+
+	return warnings, nil
+}
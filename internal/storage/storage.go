@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"oip-course/internal/models"
+	"oip-course/internal/segment"
+	"os"
+	"slices"
+	"strings"
+)
+
+// DefaultIndexPath путь к инвертированному индексу по умолчанию
+const DefaultIndexPath = "inverted_index.idx"
+
+// indexFile формат JSON файла инвертированного индекса
+type indexFile struct {
+	Metadata models.Metadata                     `json:"metadata"`
+	Postings map[string]map[string]map[int][]int `json:"postings"` // поле -> лемма -> номер страницы -> позиции
+	Lengths  map[string]map[int]int              `json:"lengths"`  // поле -> номер страницы -> кол-во токенов
+}
+
+// LoadIndex открывает инвертированный индекс: файлы .json загружаются в память целиком,
+// остальные открываются как бинарный сегмент
+func LoadIndex(filename string) (models.Index, error) {
+	if strings.HasSuffix(filename, ".json") {
+		return LoadInvertedIndex(filename)
+	}
+
+	return segment.Open(filename)
+}
+
+// SaveIndex записывает инвертированный индекс в JSON файл, если у файла расширение .json, иначе - в бинарный сегмент
+func SaveIndex(filename string, index *models.InvertedIndex) error {
+	if !strings.HasSuffix(filename, ".json") {
+		return segment.Write(filename, index)
+	}
+
+	data, err := json.MarshalIndent(indexFile{Metadata: index.Metadata(), Postings: index.GetIndex(), Lengths: index.GetLengths()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, 0755)
+}
+
+// LoadInvertedIndex загружает инвертированный индекс из JSON файла. Кроме текущего формата читаются
+// форматы прежних версий без заголовка, см. decodeLegacyIndex
+func LoadInvertedIndex(filename string) (*models.InvertedIndex, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIndex := indexFile{
+		Postings: make(map[string]map[string]map[int][]int),
+		Lengths:  make(map[string]map[int]int),
+	}
+	// Незнакомые ключи означают файл другого формата: без проверки они молча пропускаются и индекс получается пустым
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rawIndex); err != nil {
+		index, legacyErr := decodeLegacyIndex(data)
+		if legacyErr != nil {
+			return nil, fmt.Errorf("%s: unsupported index format: %w", filename, err)
+		}
+		return index, nil
+	}
+
+	if rawIndex.Metadata.Version == 0 && len(rawIndex.Postings) == 0 {
+		return nil, fmt.Errorf("%s: index has neither metadata nor postings", filename)
+	}
+
+	index := models.NewInvertedIndex(rawIndex.Postings, rawIndex.Lengths)
+	index.SetMetadata(rawIndex.Metadata)
+
+	return index, nil
+}
+
+// decodeLegacyIndex читает JSON индекс прежних версий без заголовка и длин полей:
+// поле -> лемма -> номер страницы -> позиции, до разбиения на поля - лемма -> номер страницы -> позиции,
+// а до позиций - лемма -> номера страниц. Индексы без полей относятся к тексту статьи, индекс без позиций
+// сохраняет только номера страниц, поэтому фразы и NEAR по нему ничего не находят
+func decodeLegacyIndex(data []byte) (*models.InvertedIndex, error) {
+	lengths := make(map[string]map[int]int)
+
+	var fields map[string]map[string]map[int][]int
+	if err := json.Unmarshal(data, &fields); err == nil {
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("index is empty")
+		}
+		for field := range fields {
+			if !slices.Contains(models.Fields, field) {
+				return nil, fmt.Errorf("unknown field %q", field)
+			}
+		}
+		return models.NewInvertedIndex(fields, lengths), nil
+	}
+
+	var positions map[string]map[int][]int
+	if err := json.Unmarshal(data, &positions); err == nil {
+		return models.NewInvertedIndex(map[string]map[string]map[int][]int{models.FieldBody: positions}, lengths), nil
+	}
+
+	var pages map[string][]int
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return nil, err
+	}
+
+	postings := make(map[string]map[int][]int, len(pages))
+	for lemma, lemmaPages := range pages {
+		postings[lemma] = make(map[int][]int, len(lemmaPages))
+		for _, page := range lemmaPages {
+			postings[lemma][page] = []int{}
+		}
+	}
+
+	return models.NewInvertedIndex(map[string]map[string]map[int][]int{models.FieldBody: postings}, lengths), nil
+}
+
+// LoadVectorIndex загружает TF-IDF векторы страниц из файлов lemmas_tf_idf_N.txt
+func LoadVectorIndex(dir string) (*models.VectorIndex, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := models.NewVectorIndex()
+
+	for _, item := range items {
+		var pageNum int
+		_, err := fmt.Sscanf(item.Name(), "lemmas_tf_idf_%d.txt", &pageNum)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := os.Open(dir + "/" + item.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+
+		// Каждая строка файла имеет вид "лемма idf tf-idf"
+		for scanner.Scan() {
+			var (
+				lemma      string
+				idf, tfIdf float64
+			)
+			if _, err := fmt.Sscanf(scanner.Text(), "%s %f %f", &lemma, &idf, &tfIdf); err != nil {
+				continue
+			}
+
+			index.Add(pageNum, lemma, idf, tfIdf)
+		}
+		if err = scanner.Err(); err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		file.Close()
+	}
+
+	return index, nil
+}
+
+// LoadFieldVectors возвращает TF-IDF векторы страниц по полям документа.
+// Векторы текста статьи загружаются из файлов lemmas_tf_idf_N.txt, остальных полей - строятся по инвертированному индексу
+func LoadFieldVectors(index models.Index, dir string) (map[string]models.Scorer, error) {
+	body, err := LoadVectorIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := map[string]models.Scorer{models.FieldBody: body}
+	for _, field := range models.Fields {
+		if field != models.FieldBody {
+			vectors[field] = models.FieldVectors(index, field)
+		}
+	}
+
+	return vectors, nil
+}
+
+// LoadScorers возвращает модели ранжирования по полям документа: название модели -> поле -> модель
+func LoadScorers(index models.Index, dir string, params models.BM25Params) (map[string]map[string]models.Scorer, error) {
+	vectors, err := LoadFieldVectors(index, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]map[string]models.Scorer{
+		models.ScorerTFIDF: vectors,
+		models.ScorerBM25:  models.BM25Scorers(index, params),
+	}, nil
+}
+
+// LoadPageURLs загружает из index.txt соответствие номера страницы и ее URL
+func LoadPageURLs(filename string) (map[int]string, error) {
+	urls := make(map[int]string)
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return urls, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var (
+			pageNum int
+			url     string
+		)
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %s", &pageNum, &url); err != nil {
+			continue
+		}
+
+		urls[pageNum] = url
+	}
+
+	return urls, scanner.Err()
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"oip-course/internal/analysis"
+	"oip-course/internal/models"
+	"oip-course/internal/segment"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// writeFile записывает JSON индекса во временную директорию теста
+func writeFile(t *testing.T, name string, data string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestLoadLegacyIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		field string
+		want  map[int][]int // Вхождения леммы "ген" в поле field
+		pages []int
+	}{
+		{"pages", `{"ген": [3, 1], "клетка": [2]}`, models.FieldBody, map[int][]int{1: {}, 3: {}}, []int{1, 2, 3}},
+		{"positions", `{"ген": {"1": [4, 9], "3": [0]}, "клетка": {"2": [1]}}`, models.FieldBody, map[int][]int{1: {4, 9}, 3: {0}}, []int{1, 2, 3}},
+		{"fields", `{"title": {"ген": {"1": [0]}}, "body": {"клетка": {"2": [1]}}}`, models.FieldTitle, map[int][]int{1: {0}}, []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := LoadInvertedIndex(writeFile(t, "inverted_index.json", tt.json))
+			if err != nil {
+				t.Fatalf("LoadInvertedIndex: %v", err)
+			}
+
+			if got := index.Postings(tt.field, "ген"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Postings(%s, ген) = %v, want %v", tt.field, got, tt.want)
+			}
+			if got := index.Metadata().Version; got != 0 {
+				t.Errorf("Metadata().Version = %d, want 0 for a legacy index", got)
+			}
+
+			// Конвертер записывает загруженный индекс в сегмент, термины должны сохраниться
+			filename := filepath.Join(t.TempDir(), "inverted_index.idx")
+			if err := SaveIndex(filename, index); err != nil {
+				t.Fatalf("SaveIndex: %v", err)
+			}
+			s, err := segment.Open(filename)
+			if err != nil {
+				t.Fatalf("segment.Open: %v", err)
+			}
+			defer s.Close()
+
+			for _, field := range models.Fields {
+				if got, want := s.Terms(field), index.Terms(field); !slices.Equal(got, want) && len(got)+len(want) > 0 {
+					t.Errorf("segment Terms(%s) = %v, want %v", field, got, want)
+				}
+			}
+			if got := s.AllPages(); !slices.Equal(got, tt.pages) {
+				t.Errorf("segment AllPages() = %v, want %v", got, tt.pages)
+			}
+		})
+	}
+}
+
+func TestSaveLoadIndex(t *testing.T) {
+	index := models.NewInvertedIndex(make(map[string]map[string]map[int][]int), make(map[string]map[int]int))
+	index.Add(models.FieldBody, "ген", 1, 5)
+	index.SetDocLength(models.FieldBody, 1, 10)
+	index.SetMetadata(models.Metadata{Version: models.MetadataVersion, Analyzer: analysis.DefaultConfig, DocCount: 1})
+
+	filename := filepath.Join(t.TempDir(), "inverted_index.json")
+	if err := SaveIndex(filename, index); err != nil {
+		t.Fatalf("SaveIndex: %v", err)
+	}
+
+	loaded, err := LoadInvertedIndex(filename)
+	if err != nil {
+		t.Fatalf("LoadInvertedIndex: %v", err)
+	}
+
+	if got := loaded.Postings(models.FieldBody, "ген"); !reflect.DeepEqual(got, map[int][]int{1: {5}}) {
+		t.Errorf("Postings(body, ген) = %v", got)
+	}
+	if got := loaded.DocLength(models.FieldBody, 1); got != 10 {
+		t.Errorf("DocLength(body, 1) = %d, want 10", got)
+	}
+	if got := loaded.Metadata().Analyzer.String(); got != analysis.DefaultConfig.String() {
+		t.Errorf("Metadata().Analyzer = %s, want %s", got, analysis.DefaultConfig)
+	}
+}
+
+func TestLoadInvalidIndex(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`{"metadata": {"version": 0}}`,
+		`{"author": {"ген": {"1": [0]}}}`,
+		`{"ген": "1"}`,
+		`[1, 2]`,
+	} {
+		if _, err := LoadInvertedIndex(writeFile(t, "inverted_index.json", data)); err == nil {
+			t.Errorf("LoadInvertedIndex(%s) succeeded, want an error", data)
+		}
+	}
+}
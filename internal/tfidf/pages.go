@@ -0,0 +1,120 @@
+package tfidf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Page входные данные страницы для подсчета TF-IDF
+type Page struct {
+	Number int
+	Tokens []string      // Токены в порядке следования на странице, их кол-во - знаменатель TF
+	Lemmas []LemmaTokens // Леммы страницы и их токены
+}
+
+// LemmaTokens лемма и токены страницы, которые к ней приводятся
+type LemmaTokens struct {
+	Lemma  string
+	Tokens []string
+}
+
+// Compute считает частоты токенов и лемм всех страниц за один проход.
+// Частота леммы на странице - сумма частот ее токенов, TF считается относительно кол-ва токенов страницы
+func Compute(pages []Page) (tokens *Corpus, lemmas *Corpus) {
+	tokens, lemmas = NewCorpus(), NewCorpus()
+
+	for _, page := range pages {
+		tokens.SetLength(page.Number, len(page.Tokens))
+		lemmas.SetLength(page.Number, len(page.Tokens))
+
+		counts := make(map[string]int)
+		for _, token := range page.Tokens {
+			tokens.Add(page.Number, token, 1)
+			counts[token]++
+		}
+
+		for _, lemma := range page.Lemmas {
+			var count int
+			for _, token := range lemma.Tokens {
+				count += counts[token]
+			}
+			lemmas.Add(page.Number, lemma.Lemma, count)
+		}
+	}
+
+	return tokens, lemmas
+}
+
+// LoadPages читает токены страниц из tokensDir/tokens_N.txt и их леммы из lemmasDir/lemmas_N.txt.
+// Оба файла записывает токенайзер, поэтому TF считается по тем же токенам, что попадают в индекс и запросы
+func LoadPages(tokensDir, lemmasDir string) ([]Page, error) {
+	items, err := os.ReadDir(tokensDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]Page, 0, len(items))
+	for _, item := range items {
+		var page Page
+		if _, err := fmt.Sscanf(item.Name(), "tokens_%d.txt", &page.Number); err != nil {
+			return nil, err
+		}
+
+		page.Tokens, err = readTokens(tokensDir + "/" + item.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		page.Lemmas, err = ReadLemmas(fmt.Sprintf("%s/lemmas_%d.txt", lemmasDir, page.Number))
+		if err != nil {
+			return nil, err
+		}
+
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+// readTokens читает токены страницы, по одному на строке
+func readTokens(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if token := strings.TrimSpace(scanner.Text()); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, scanner.Err()
+}
+
+// ReadLemmas читает леммы страницы, каждая строка файла имеет вид "лемма: токен1 токен2"
+func ReadLemmas(filename string) ([]LemmaTokens, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lemmas []LemmaTokens
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lemma, tokens, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(lemma) == "" {
+			continue
+		}
+
+		lemmas = append(lemmas, LemmaTokens{Lemma: strings.TrimSpace(lemma), Tokens: strings.Fields(tokens)})
+	}
+
+	return lemmas, scanner.Err()
+}
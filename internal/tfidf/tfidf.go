@@ -0,0 +1,81 @@
+package tfidf
+
+import "math"
+
+// Corpus частоты терминов по страницам и документные частоты терминов корпуса.
+// Частоты накапливаются за один проход по терминам страниц, после чего TF-IDF любого термина считается за O(1)
+type Corpus struct {
+	pages map[int]*pageTerms
+	df    map[string]int // термин -> кол-во страниц, на которых он встречается
+}
+
+// pageTerms частоты терминов страницы
+type pageTerms struct {
+	counts map[string]int // термин -> кол-во вхождений
+	order  []string       // различные термины в порядке первого вхождения
+	length int            // кол-во токенов страницы, знаменатель TF
+}
+
+// Weight TF-IDF термина на странице
+type Weight struct {
+	Term  string
+	IDF   float64
+	TFIDF float64
+}
+
+func NewCorpus() *Corpus {
+	return &Corpus{
+		pages: make(map[int]*pageTerms),
+		df:    make(map[string]int),
+	}
+}
+
+// page возвращает частоты терминов страницы, добавляя страницу в корпус при первом обращении
+func (c *Corpus) page(page int) *pageTerms {
+	terms, ok := c.pages[page]
+	if !ok {
+		terms = &pageTerms{counts: make(map[string]int)}
+		c.pages[page] = terms
+	}
+
+	return terms
+}
+
+// Add добавляет count вхождений термина на страницу
+func (c *Corpus) Add(page int, term string, count int) {
+	terms := c.page(page)
+	if _, ok := terms.counts[term]; !ok {
+		terms.order = append(terms.order, term)
+		c.df[term]++
+	}
+
+	terms.counts[term] += count
+}
+
+// SetLength задает кол-во токенов страницы, относительно которого считается TF
+func (c *Corpus) SetLength(page int, length int) {
+	c.page(page).length = length
+}
+
+// Weights возвращает TF-IDF различных терминов страницы в порядке их первого вхождения.
+// TF - доля вхождений термина среди токенов страницы, IDF - натуральный логарифм отношения кол-ва страниц корпуса
+// к кол-ву страниц с термином
+func (c *Corpus) Weights(page int) []Weight {
+	terms, ok := c.pages[page]
+	if !ok {
+		return nil
+	}
+
+	weights := make([]Weight, 0, len(terms.order))
+	for _, term := range terms.order {
+		var tf float64
+		if terms.length > 0 {
+			tf = float64(terms.counts[term]) / float64(terms.length)
+		}
+		idf := math.Log(float64(len(c.pages)) / float64(c.df[term]))
+
+		weights = append(weights, Weight{Term: term, IDF: idf, TFIDF: tf * idf})
+	}
+
+	return weights
+}
@@ -0,0 +1,257 @@
+package tfidf
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestWeights(t *testing.T) {
+	pages := []Page{
+		{
+			Number: 1,
+			Tokens: []string{"гены", "клетка", "гены", "ген"},
+			Lemmas: []LemmaTokens{{Lemma: "ген", Tokens: []string{"гены", "ген"}}, {Lemma: "клетка", Tokens: []string{"клетка"}}},
+		},
+		{
+			Number: 2,
+			Tokens: []string{"клетка", "белок"},
+			Lemmas: []LemmaTokens{{Lemma: "клетка", Tokens: []string{"клетка"}}, {Lemma: "белок", Tokens: []string{"белок"}}},
+		},
+	}
+
+	tokens, lemmas := Compute(pages)
+
+	tests := []struct {
+		name   string
+		corpus *Corpus
+		page   int
+		want   []Weight
+	}{
+		{"tokens", tokens, 1, []Weight{
+			{Term: "гены", IDF: math.Log(2), TFIDF: 2.0 / 4 * math.Log(2)},
+			{Term: "клетка", IDF: 0, TFIDF: 0},
+			{Term: "ген", IDF: math.Log(2), TFIDF: 1.0 / 4 * math.Log(2)},
+		}},
+		{"lemmas", lemmas, 1, []Weight{
+			{Term: "ген", IDF: math.Log(2), TFIDF: 3.0 / 4 * math.Log(2)},
+			{Term: "клетка", IDF: 0, TFIDF: 0},
+		}},
+		{"second page", lemmas, 2, []Weight{
+			{Term: "клетка", IDF: 0, TFIDF: 0},
+			{Term: "белок", IDF: math.Log(2), TFIDF: 1.0 / 2 * math.Log(2)},
+		}},
+		{"missing page", lemmas, 3, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.corpus.Weights(tt.page)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Weights(%d) = %v, want %v", tt.page, got, tt.want)
+			}
+
+			for i := range got {
+				if got[i].Term != tt.want[i].Term || !almostEqual(got[i].IDF, tt.want[i].IDF) || !almostEqual(got[i].TFIDF, tt.want[i].TFIDF) {
+					t.Errorf("Weights(%d)[%d] = %+v, want %+v", tt.page, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// almostEqual сравнивает числа с точностью до ошибки округления
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+// benchmarkPages генерирует страницы из случайных слов с частотами по закону Ципфа, формы слова - слово с окончанием
+func benchmarkPages(n, length int) []Page {
+	rng := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(rng, 1.1, 1, 5_000)
+	endings := []string{"", "а", "ы", "ом"}
+
+	pages := make([]Page, 0, n)
+	for number := 1; number <= n; number++ {
+		page := Page{Number: number}
+		forms := make(map[string][]string)
+		var order []string
+
+		for range length {
+			lemma := fmt.Sprintf("слово%d", zipf.Uint64())
+			token := lemma + endings[rng.Intn(len(endings))]
+			page.Tokens = append(page.Tokens, token)
+
+			if _, ok := forms[lemma]; !ok {
+				order = append(order, lemma)
+			}
+			if !slices.Contains(forms[lemma], token) {
+				forms[lemma] = append(forms[lemma], token)
+			}
+		}
+
+		for _, lemma := range order {
+			page.Lemmas = append(page.Lemmas, LemmaTokens{Lemma: lemma, Tokens: forms[lemma]})
+		}
+		pages = append(pages, page)
+	}
+
+	return pages
+}
+
+func BenchmarkCompute(b *testing.B) {
+	pages := benchmarkPages(100, 1_000)
+	b.ResetTimer()
+
+	for range b.N {
+		tokens, lemmas := Compute(pages)
+		for _, page := range pages {
+			tokens.Weights(page.Number)
+			lemmas.Weights(page.Number)
+		}
+	}
+}
+
+// BenchmarkComputeLegacy прежний подсчет, перебиравший страницы для каждого вхождения токена.
+// Он квадратичен, поэтому страниц меньше, чем в BenchmarkCompute: сравнивать нужно с BenchmarkComputeSmall
+func BenchmarkComputeLegacy(b *testing.B) {
+	pages := benchmarkPages(20, 500)
+	b.ResetTimer()
+
+	for range b.N {
+		computeLegacy(pages)
+	}
+}
+
+func BenchmarkComputeSmall(b *testing.B) {
+	pages := benchmarkPages(20, 500)
+	b.ResetTimer()
+
+	for range b.N {
+		tokens, lemmas := Compute(pages)
+		for _, page := range pages {
+			tokens.Weights(page.Number)
+			lemmas.Weights(page.Number)
+		}
+	}
+}
+
+// Страницы tokens/ и lemmas/ из корня репозитория, загружаются один раз для обоих бенчмарков корпуса
+var (
+	corpusOnce  sync.Once
+	corpus      []Page
+	corpusError error
+)
+
+// corpusPages возвращает страницы корпуса репозитория или пропускает бенчмарк, если их нет
+func corpusPages(b *testing.B) []Page {
+	corpusOnce.Do(func() {
+		corpus, corpusError = LoadPages("../../tokens", "../../lemmas")
+	})
+	if corpusError != nil {
+		b.Skipf("corpus is not available: %v", corpusError)
+	}
+
+	return corpus
+}
+
+// BenchmarkCorpusCompute и BenchmarkCorpusComputeLegacy сравнивают подсчеты на страницах корпуса репозитория
+func BenchmarkCorpusCompute(b *testing.B) {
+	pages := corpusPages(b)
+	b.ResetTimer()
+
+	for range b.N {
+		tokens, lemmas := Compute(pages)
+		for _, page := range pages {
+			tokens.Weights(page.Number)
+			lemmas.Weights(page.Number)
+		}
+	}
+}
+
+func BenchmarkCorpusComputeLegacy(b *testing.B) {
+	pages := corpusPages(b)
+	b.ResetTimer()
+
+	for range b.N {
+		computeLegacy(pages)
+	}
+}
+
+// computeLegacy повторяет прежний подсчет: для каждого вхождения токена заново считаются
+// его вхождения на странице и страницы корпуса, на которых он встречается
+func computeLegacy(pages []Page) {
+	allTokens := make(map[int][]string, len(pages))
+	for _, page := range pages {
+		allTokens[page.Number] = page.Tokens
+	}
+
+	for _, page := range pages {
+		for _, token := range page.Tokens {
+			tf := float64(countTokenOccurrencesInPage(page.Tokens, token)) / float64(len(page.Tokens))
+			idf := math.Log(float64(len(pages)) / float64(countPagesWithToken(allTokens, token)))
+			_ = tf * idf
+		}
+
+		for _, lemma := range page.Lemmas {
+			var lemmaTf float64
+			for _, token := range lemma.Tokens {
+				lemmaTf += float64(countTokenOccurrencesInPage(page.Tokens, token)) / float64(len(page.Tokens))
+			}
+
+			lemmaIdf := math.Log(float64(len(pages)) / float64(countPagesWithLemma(allTokens, lemma.Tokens)))
+			_ = lemmaTf * lemmaIdf
+		}
+	}
+}
+
+// countTokenOccurrencesInPage возвращает количество появлений токена на странице
+func countTokenOccurrencesInPage(pageWords []string, token string) int {
+	count := 0
+	for _, word := range pageWords {
+		if word == token {
+			count++
+		}
+	}
+
+	return count
+}
+
+// countPagesWithToken возвращает количество страниц, в которых встречается токен
+func countPagesWithToken(allTokens map[int][]string, token string) int {
+	count := 0
+	for _, tokens := range allTokens {
+		for _, t := range tokens {
+			if t == token {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+// countPagesWithLemma возвращает количество страниц, в которых встречается хотя бы один токен леммы
+func countPagesWithLemma(allTokens map[int][]string, lemmaTokens []string) int {
+	count := 0
+	for _, tokens := range allTokens {
+		for _, t := range tokens {
+			tokenFound := false
+			for _, lemmaToken := range lemmaTokens {
+				if t == lemmaToken {
+					count++
+					tokenFound = true
+				}
+			}
+			if tokenFound {
+				break
+			}
+		}
+	}
+
+	return count
+}